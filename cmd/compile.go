@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"os"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/orchestrator"
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// runs the compile subcommand with the given arguments, returning the process exit code.
+// the dictionaries are compiled into an index file, which later runs load with --index instead of the dictionaries.
+func runCompile(args []string) int {
+	flags := flag.NewFlagSet("compile", flag.ExitOnError)
+	var dictionaries dictionaryFlags
+	dictionaries.register(flags, "Path to dictionary file to compile, repeat as --dictionary name=path to compile several named dictionaries")
+	outputPath := flags.String("output", "", "Path of the index file to write")
+	matchMode := flags.String("mode", "", "Matching mode the index is compiled for: anagram, fixed-ends or exact (defaults to MATCH_MODE, or anagram)")
+	normalization := flags.String("normalize", "", "Unicode normalization applied to dictionary words: none, nfc, nfd, nfkc or nfkd (defaults to NORMALIZATION, or none)")
+	foldAccents := flags.Bool("fold-accents", false, "Ignore accents when matching (defaults to FOLD_ACCENTS)")
+	foldCase := flags.Bool("ignore-case", false, "Ignore case when matching (defaults to FOLD_CASE)")
+	ignorePunctuation := flags.Bool("ignore-punctuation", false, "Leave punctuation and whitespace out of dictionary words (defaults to IGNORE_PUNCTUATION)")
+	strict := flags.Bool("strict", false, "Fail when a dictionary word violates a constraint, rather than dropping it (defaults to STRICT)")
+	flags.Parse(args)
+
+	if len(dictionaries.values) == 0 || *outputPath == "" {
+		utils.Log.Fatalf("Usage: %s compile --dictionary [[NAME=]PATH TO DICTIONARY FILE]... --output [PATH TO INDEX FILE]", os.Args[0])
+	}
+	if err := dictionaries.check(); err != nil {
+		utils.Log.Fatal(err)
+	}
+
+	appConfig := config.NewAppConfig()
+	if *matchMode != "" {
+		appConfig.Mode = *matchMode
+	}
+	if *normalization != "" {
+		appConfig.Normalization = *normalization
+	}
+	if *foldAccents {
+		appConfig.FoldAccents = true
+	}
+	if *foldCase {
+		appConfig.FoldCase = true
+	}
+	if *ignorePunctuation {
+		appConfig.IgnorePunctuation = true
+	}
+	if *strict {
+		appConfig.DictionaryConfig.Strict = true
+	}
+	opts := orchestrator.Options{Config: appConfig}
+	dictionaries.apply(&opts)
+
+	ix, err := orchestrator.Compile(context.Background(), opts)
+	if err != nil {
+		utils.Log.Fatal(err)
+	}
+	if err := ix.WriteFile(*outputPath); err != nil {
+		utils.Log.Fatal(err)
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"indexPath": *outputPath,
+		"wordCount": len(ix.Words),
+		"fileCount": len(ix.Files),
+	}).Info("Compiled dictionary index")
+	return 0
+}
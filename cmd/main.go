@@ -1,33 +1,312 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
+	"fmt"
 	"os"
+	"path/filepath"
+	"strings"
+	"unicode"
 
 	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
 	"github.com/1x-eng/cipherlex/pkg/orchestrator"
+	"github.com/1x-eng/cipherlex/pkg/output"
 	"github.com/1x-eng/cipherlex/pkg/utils"
 )
 
 func main() {
-	dictionaryFilePath := flag.String("dictionary", "", "Path to dictionary file")
-	inputFilePath := flag.String("input", "", "Path to input file")
+	if len(os.Args) > 1 && os.Args[1] == "validate" {
+		os.Exit(runValidate(os.Args[2:]))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "compile" {
+		os.Exit(runCompile(os.Args[2:]))
+	}
+
+	var dictionaries dictionaryFlags
+	dictionaries.register(flag.CommandLine, "Path to dictionary file, repeat as --dictionary name=path to match against several named dictionaries")
+	inputFilePath := flag.String("input", "", "Path to input file, - reads the input from stdin")
+	indexPath := flag.String("index", "", "Path to a dictionary index written by the compile subcommand, used instead of --dictionary")
+	matchMode := flag.String("mode", "", "Matching mode: anagram, fixed-ends or exact (defaults to MATCH_MODE, or anagram)")
+	matchEngine := flag.String("engine", "", "Matching engine: trie, window, aho-corasick, fuzzy or gapped, aho-corasick only in exact mode (defaults to MATCH_ENGINE, or trie)")
+	maxDistance := flag.Int("max-distance", -1, "Maximum distance between an occurrence and a dictionary word with the fuzzy engine (defaults to MAX_DISTANCE, or 1)")
+	maxSpan := flag.Int("max-span", 0, "Maximum number of characters a word's letters may be spread across with the gapped engine (defaults to MAX_SPAN, or 20)")
+	trieImpl := flag.String("trie", "", "Trie implementation holding the dictionary: map, or compact to save memory on large dictionaries (defaults to TRIE_IMPL, or map)")
+	distanceMetric := flag.String("distance-metric", "", "Distance used by the fuzzy engine: levenshtein, only in exact mode, or multiset (defaults to DISTANCE_METRIC, or the mode's default)")
+	exactEngine := flag.String("exact-engine", "", "Engine finding the words matched exactly, in exact mode or annotated exact: trie, window or aho-corasick (defaults to EXACT_ENGINE, or the --engine)")
+	outputFormat := flag.String("output-format", "", "Output format: text, json, ndjson or csv (defaults to OUTPUT_FORMAT, or text)")
+	normalization := flag.String("normalize", "", "Unicode normalization applied before matching: none, nfc, nfd, nfkc or nfkd (defaults to NORMALIZATION, or none)")
+	foldAccents := flag.Bool("fold-accents", false, "Ignore accents when matching, e.g. cafe matches café (defaults to FOLD_ACCENTS)")
+	foldCase := flag.Bool("ignore-case", false, "Ignore case when matching, e.g. Apple matches pplea (defaults to FOLD_CASE)")
+	ignorePunctuation := flag.Bool("ignore-punctuation", false, "Skip punctuation and whitespace inside input lines when forming candidate substrings (defaults to IGNORE_PUNCTUATION)")
+	numbering := flag.String("numbering", "", "Case numbering: case numbers loaded lines 1, 2, 3..., line numbers results after their input line and reports skipped lines (defaults to NUMBERING, or case)")
+	longLines := flag.String("long-lines", "", "What to do with input lines longer than MAX_LINE_LENGTH: reject, truncate or split (defaults to LONG_LINE_POLICY, or reject)")
+	strict := flag.Bool("strict", false, "Fail when a dictionary word or input line violates a constraint, rather than dropping it (defaults to STRICT)")
+	stream := flag.Bool("stream", false, "Stream the input, writing each result as soon as it is ready and ignoring MAX_LINE_COUNT")
+	timeout := flag.Duration("timeout", 0, "Maximum duration of the run, e.g. 30s, partial results are written once it elapses (0 means no timeout)")
 
 	flag.Parse()
 
-	if *dictionaryFilePath == "" || *inputFilePath == "" {
-		utils.Log.Fatalf("Usage: %s --dictionary [PATH TO DICTIONARY FILE] --input [PATH TO INPUT FILE]", os.Args[0])
+	if (len(dictionaries.values) == 0 && *indexPath == "") || *inputFilePath == "" {
+		utils.Log.Fatalf("Usage: %s (--dictionary [[NAME=]PATH TO DICTIONARY FILE]... | --index [PATH TO INDEX FILE]) --input [PATH TO INPUT FILE]", os.Args[0])
+	}
+	if err := dictionaries.check(); err != nil {
+		utils.Log.Fatal(err)
 	}
 
 	utils.Log.Info("Loading cipherlex configuration")
 	appConfig := config.NewAppConfig()
+	if *matchMode != "" {
+		appConfig.Mode = *matchMode
+	}
+	if *matchEngine != "" {
+		appConfig.Engine = *matchEngine
+	}
+	if *exactEngine != "" {
+		appConfig.ExactEngine = *exactEngine
+	}
+	if *maxDistance >= 0 {
+		appConfig.MaxDistance = *maxDistance
+	}
+	if *maxSpan > 0 {
+		appConfig.MaxSpan = *maxSpan
+	}
+	if *trieImpl != "" {
+		appConfig.TrieImpl = *trieImpl
+	}
+	if *distanceMetric != "" {
+		appConfig.DistanceMetric = *distanceMetric
+	}
+	if *outputFormat != "" {
+		appConfig.Format = *outputFormat
+	}
+	if *normalization != "" {
+		appConfig.Normalization = *normalization
+	}
+	if *foldAccents {
+		appConfig.FoldAccents = true
+	}
+	if *foldCase {
+		appConfig.FoldCase = true
+	}
+	if *ignorePunctuation {
+		appConfig.IgnorePunctuation = true
+	}
+	if *longLines != "" {
+		appConfig.LongLinePolicy = *longLines
+	}
+	if *numbering != "" {
+		appConfig.Numbering = *numbering
+	}
+	if *strict {
+		appConfig.DictionaryConfig.Strict = true
+		appConfig.InputConfig.Strict = true
+	}
+	format, err := output.ParseFormat(appConfig.Format)
+	if err != nil {
+		utils.Log.Fatal(err)
+	}
+	formatter, err := output.NewFormatter(format, os.Stdout)
+	if err != nil {
+		utils.Log.Fatal(err)
+	}
 
 	utils.Log.WithFields(map[string]interface{}{
-		"dictionaryPath": dictionaryFilePath,
-		"inputPath":      inputFilePath,
+		"dictionaries":      dictionaries.String(),
+		"indexPath":         *indexPath,
+		"inputPath":         inputFilePath,
+		"mode":              appConfig.Mode,
+		"engine":            appConfig.Engine,
+		"exactEngine":       appConfig.ExactEngine,
+		"maxDistance":       appConfig.MaxDistance,
+		"distanceMetric":    appConfig.DistanceMetric,
+		"maxSpan":           appConfig.MaxSpan,
+		"trieImpl":          appConfig.TrieImpl,
+		"outputFormat":      appConfig.Format,
+		"numbering":         appConfig.Numbering,
+		"normalization":     appConfig.Normalization,
+		"foldAccents":       appConfig.FoldAccents,
+		"foldCase":          appConfig.FoldCase,
+		"ignorePunctuation": appConfig.IgnorePunctuation,
+		"longLinePolicy":    appConfig.LongLinePolicy,
+		"strict":            *strict,
+		"stream":            *stream,
 	}).Info("Starting processing")
 
-	orchestrator.Processor(*dictionaryFilePath, *inputFilePath, appConfig)
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	opts := orchestrator.Options{
+		InputPath: *inputFilePath,
+		IndexPath: *indexPath,
+		Config:    appConfig,
+	}
+	dictionaries.apply(&opts)
+	var summary output.Summary
+	if *stream {
+		summary, err = orchestrator.RunStream(ctx, opts, formatter)
+	} else {
+		summary, err = runAndWrite(ctx, opts, formatter)
+	}
+	if summary.Status == output.StatusTimedOut {
+		utils.Log.WithFields(map[string]interface{}{
+			"timeout":        timeout.String(),
+			"processedLines": summary.Lines,
+		}).Fatal("Cipherlex timed out, results are partial")
+	}
+	var diagErr *diagnostics.Error
+	if errors.As(err, &diagErr) {
+		printDiagnostics(os.Stderr, diagErr.Diagnostics)
+		utils.Log.Fatalf("Found %d constraint violation(s) in strict mode", len(diagErr.Diagnostics))
+	}
+	if err != nil {
+		utils.Log.Fatal(err)
+	}
 
 	utils.Log.Info("Cipherlex completed successfully")
 }
+
+// runs cipherlex over the whole input and then writes the report, partial reports of timed out runs are written too.
+func runAndWrite(ctx context.Context, opts orchestrator.Options, formatter output.Formatter) (output.Summary, error) {
+	report, err := orchestrator.Run(ctx, opts)
+	if err != nil && report.Summary.Status != output.StatusTimedOut {
+		return report.Summary, err
+	}
+	if writeErr := orchestrator.Write(formatter, report); writeErr != nil {
+		return report.Summary, fmt.Errorf("failed to write results: %w", writeErr)
+	}
+	return report.Summary, err
+}
+
+// dictionaryFlags collects the values of the repeatable --dictionary flag, each either "name=path" or a bare path,
+// along with the names given to them by the --dictionary-name flag.
+type dictionaryFlags struct {
+	values []dictionaryValue
+	name   string // name given by --dictionary-name to the next --dictionary value
+}
+
+// dictionaryValue is a --dictionary value split into its name, empty for a bare path, and its path.
+type dictionaryValue struct {
+	name string
+	path string
+}
+
+// dictionaryNameFlag is the --dictionary-name flag, naming the next value of the given dictionaryFlags.
+type dictionaryNameFlag struct {
+	dictionaries *dictionaryFlags
+}
+
+// registers the --dictionary flag with the given usage, and the --dictionary-name flag, on the given flag set.
+func (d *dictionaryFlags) register(flags *flag.FlagSet, usage string) {
+	flags.Var(d, "dictionary", usage)
+	flags.Var(dictionaryNameFlag{dictionaries: d}, "dictionary-name", "Name of the next --dictionary, whose value is then used as a path as is, even when it holds an =")
+}
+
+// String returns the flag values joined by commas.
+func (d *dictionaryFlags) String() string {
+	values := make([]string, len(d.values))
+	for i, value := range d.values {
+		values[i] = value.path
+		if value.name != "" {
+			values[i] = value.name + "=" + value.path
+		}
+	}
+	return strings.Join(values, ",")
+}
+
+// Set appends a flag value, named by a preceding --dictionary-name or else split by splitDictionary.
+func (d *dictionaryFlags) Set(value string) error {
+	if value == "" {
+		return fmt.Errorf("dictionary must not be empty")
+	}
+	dict := dictionaryValue{name: d.name, path: value}
+	if d.name == "" {
+		dict = splitDictionary(value)
+	}
+	d.values = append(d.values, dict)
+	d.name = ""
+	return nil
+}
+
+// String returns no default value.
+func (n dictionaryNameFlag) String() string {
+	return ""
+}
+
+// Set names the next --dictionary value.
+func (n dictionaryNameFlag) Set(name string) error {
+	if name == "" {
+		return fmt.Errorf("dictionary name must not be empty")
+	}
+	if n.dictionaries.name != "" {
+		return fmt.Errorf("dictionary name %q is not followed by a --dictionary", n.dictionaries.name)
+	}
+	n.dictionaries.name = name
+	return nil
+}
+
+// returns an error if the last --dictionary-name is not followed by a --dictionary.
+func (d *dictionaryFlags) check() error {
+	if d.name != "" {
+		return fmt.Errorf("dictionary name %q is not followed by a --dictionary", d.name)
+	}
+	return nil
+}
+
+// sets the dictionaries of the given options, a single bare path is used as is and anything else as several named dictionaries.
+func (d *dictionaryFlags) apply(opts *orchestrator.Options) {
+	if len(d.values) == 1 && d.values[0].name == "" {
+		opts.DictionaryPath = d.values[0].path
+	} else {
+		opts.Dictionaries = d.sources()
+	}
+}
+
+// converts the flag values into named dictionaries, a bare path is named after its file name without extension.
+func (d *dictionaryFlags) sources() []orchestrator.DictionarySource {
+	sources := make([]orchestrator.DictionarySource, len(d.values))
+	for i, value := range d.values {
+		name := value.name
+		if name == "" {
+			name = strings.TrimSuffix(filepath.Base(value.path), filepath.Ext(value.path))
+		}
+		sources[i] = orchestrator.DictionarySource{Name: name, Path: value.path}
+	}
+	return sources
+}
+
+// utility to split a --dictionary value into a name and a path.
+// the value is "name=path" only when the part before the first = is an identifier and the whole value is not an existing file,
+// any other value is a bare path, so paths holding an = are used as they are.
+func splitDictionary(value string) dictionaryValue {
+	name, path, found := strings.Cut(value, "=")
+	if !found || !isIdentifier(name) {
+		return dictionaryValue{path: value}
+	}
+	if _, err := os.Stat(value); err == nil {
+		return dictionaryValue{path: value}
+	}
+	return dictionaryValue{name: name, path: path}
+}
+
+// utility to check whether the given string is an identifier: a letter or underscore, followed by letters, digits, underscores and hyphens.
+func isIdentifier(s string) bool {
+	if s == "" {
+		return false
+	}
+	for i, r := range s {
+		switch {
+		case unicode.IsLetter(r), r == '_':
+		case i > 0 && (unicode.IsDigit(r) || r == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
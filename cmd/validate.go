@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
+	"github.com/1x-eng/cipherlex/pkg/orchestrator"
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// runs the validate subcommand with the given arguments, returning the process exit code.
+// every dictionary word and input line violating a constraint is written to stdout, and the exit code is 1 when there is any.
+func runValidate(args []string) int {
+	flags := flag.NewFlagSet("validate", flag.ExitOnError)
+	var dictionaries dictionaryFlags
+	dictionaries.register(flags, "Path to dictionary file to validate, repeat as --dictionary name=path to validate several")
+	inputFilePath := flags.String("input", "", "Path to input file to validate, - reads the input from stdin")
+	outputFormat := flags.String("output-format", "text", "Output format of the diagnostics: text or json")
+	ignorePunctuation := flags.Bool("ignore-punctuation", false, "Leave punctuation and whitespace out of input line lengths (defaults to IGNORE_PUNCTUATION)")
+	longLines := flags.String("long-lines", "", "What to do with input lines longer than MAX_LINE_LENGTH: reject, truncate or split (defaults to LONG_LINE_POLICY, or reject)")
+	flags.Parse(args)
+
+	if len(dictionaries.values) == 0 && *inputFilePath == "" {
+		utils.Log.Fatalf("Usage: %s validate [--dictionary [NAME=]PATH TO DICTIONARY FILE]... [--input PATH TO INPUT FILE]", os.Args[0])
+	}
+	if err := dictionaries.check(); err != nil {
+		utils.Log.Fatal(err)
+	}
+	if *outputFormat != "text" && *outputFormat != "json" {
+		utils.Log.Fatalf("unknown output format %q, expected text or json", *outputFormat)
+	}
+
+	appConfig := config.NewAppConfig()
+	if *ignorePunctuation {
+		appConfig.IgnorePunctuation = true
+	}
+	if *longLines != "" {
+		appConfig.LongLinePolicy = *longLines
+	}
+	opts := orchestrator.Options{
+		InputPath: *inputFilePath,
+		Config:    appConfig,
+	}
+	dictionaries.apply(&opts)
+
+	diags, err := orchestrator.Validate(context.Background(), opts)
+	if err != nil {
+		utils.Log.Fatal(err)
+	}
+
+	if *outputFormat == "json" {
+		if diags == nil {
+			diags = []diagnostics.Diagnostic{}
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(diags); err != nil {
+			utils.Log.Fatal(err)
+		}
+	} else {
+		printDiagnostics(os.Stdout, diags)
+	}
+
+	utils.Log.WithField("violationCount", len(diags)).Info("Validation completed")
+	if len(diags) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// writes the given diagnostics one per line.
+func printDiagnostics(w io.Writer, diags []diagnostics.Diagnostic) {
+	for _, d := range diags {
+		fmt.Fprintln(w, d)
+	}
+}
@@ -2,7 +2,10 @@ module github.com/1x-eng/cipherlex
 
 go 1.20
 
-require github.com/sirupsen/logrus v1.9.3
+require (
+	github.com/sirupsen/logrus v1.9.3
+	golang.org/x/text v0.14.0
+)
 
 require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
@@ -12,5 +15,5 @@ require (
 
 require (
 	github.com/stretchr/testify v1.8.4
-	golang.org/x/sys v0.0.0-20220715151400-c0bba94af5f8 // indirect
+	golang.org/x/sys v0.5.0 // indirect
 )
@@ -2,30 +2,65 @@ package config
 
 import (
 	"os"
+	"runtime"
 	"strconv"
 )
 
 // AppConfig holds all application-wide configuration settings.
 type AppConfig struct {
-	DictionaryConfig
-	InputConfig
+	DictionaryConfig `json:"dictionary"`
+	InputConfig      `json:"input"`
+	MatcherConfig    `json:"matcher"`
+	OutputConfig     `json:"output"`
+	TextConfig       `json:"text"`
 }
 
 // DictionaryConfig holds configuration settings specific to dictionary processing.
 type DictionaryConfig struct {
-	MinWordLength     int
-	MaxWordLength     int
-	MaxDictionarySize int
+	MinWordLength     int  `json:"min_word_length"`
+	MaxWordLength     int  `json:"max_word_length"`
+	MaxDictionarySize int  `json:"max_dictionary_size"`
+	MaxTokenSize      int  `json:"max_token_size"`
+	Strict            bool `json:"strict"`
 }
 
 // InputConfig holds configuration settings specific to input processing.
 type InputConfig struct {
-	MinLineLength             int
-	MaxLineLength             int
-	MaxLineCount              int
-	MinChunkSize              int
-	MaxChunkSize              int
-	ChunkSizeAdjustmentFactor int
+	MinLineLength             int    `json:"min_line_length"`
+	MaxLineLength             int    `json:"max_line_length"`
+	MaxLineCount              int    `json:"max_line_count"`
+	MinChunkSize              int    `json:"min_chunk_size"`
+	MaxChunkSize              int    `json:"max_chunk_size"`
+	ChunkSizeAdjustmentFactor int    `json:"chunk_size_adjustment_factor"`
+	IgnorePunctuation         bool   `json:"ignore_punctuation"`
+	Strict                    bool   `json:"strict"`
+	MaxTokenSize              int    `json:"max_token_size"`
+	LongLinePolicy            string `json:"long_line_policy"`
+}
+
+// MatcherConfig holds configuration settings specific to word matching.
+type MatcherConfig struct {
+	Mode           string `json:"mode"`
+	Engine         string `json:"engine"`
+	ExactEngine    string `json:"exact_engine"`
+	Workers        int    `json:"workers"`
+	MaxDistance    int    `json:"max_distance"`
+	DistanceMetric string `json:"distance_metric"`
+	MaxSpan        int    `json:"max_span"`
+	TrieImpl       string `json:"trie_impl"`
+}
+
+// TextConfig holds configuration settings specific to how text is normalized before matching.
+type TextConfig struct {
+	Normalization string `json:"normalization"`
+	FoldAccents   bool   `json:"fold_accents"`
+	FoldCase      bool   `json:"fold_case"`
+}
+
+// OutputConfig holds configuration settings specific to writing results.
+type OutputConfig struct {
+	Format    string `json:"format"`
+	Numbering string `json:"numbering"`
 }
 
 // NewAppConfig creates a new AppConfig with settings from environment variables.
@@ -35,6 +70,8 @@ func NewAppConfig() AppConfig {
 			MinWordLength:     getEnvAsInt("MIN_WORD_LENGTH", 2),
 			MaxWordLength:     getEnvAsInt("MAX_WORD_LENGTH", 20),
 			MaxDictionarySize: getEnvAsInt("MAX_DICTIONARY_SIZE", 100),
+			MaxTokenSize:      getEnvAsInt("MAX_TOKEN_SIZE", 1024*1024),
+			Strict:            getEnvAsBool("STRICT", false),
 		},
 		InputConfig: InputConfig{
 			MinLineLength:             getEnvAsInt("MIN_LINE_LENGTH", 2),
@@ -43,6 +80,29 @@ func NewAppConfig() AppConfig {
 			MinChunkSize:              getEnvAsInt("MIN_CHUNK_SIZE", 10),
 			MaxChunkSize:              getEnvAsInt("MAX_CHUNK_SIZE", 100),
 			ChunkSizeAdjustmentFactor: getEnvAsInt("CHUNK_SIZE_ADJUSTMENT_FACTOR", 4), // chosing a heuristic value of 4, but this is a line in the sand.
+			IgnorePunctuation:         getEnvAsBool("IGNORE_PUNCTUATION", false),
+			Strict:                    getEnvAsBool("STRICT", false),
+			MaxTokenSize:              getEnvAsInt("MAX_TOKEN_SIZE", 1024*1024),
+			LongLinePolicy:            getEnvAsString("LONG_LINE_POLICY", "reject"),
+		},
+		MatcherConfig: MatcherConfig{
+			Mode:           getEnvAsString("MATCH_MODE", "anagram"),
+			Engine:         getEnvAsString("MATCH_ENGINE", "trie"),
+			ExactEngine:    getEnvAsString("EXACT_ENGINE", ""),
+			Workers:        getEnvAsInt("WORKERS", runtime.GOMAXPROCS(0)),
+			MaxDistance:    getEnvAsInt("MAX_DISTANCE", 1),
+			DistanceMetric: getEnvAsString("DISTANCE_METRIC", ""),
+			MaxSpan:        getEnvAsInt("MAX_SPAN", 20),
+			TrieImpl:       getEnvAsString("TRIE_IMPL", "map"),
+		},
+		OutputConfig: OutputConfig{
+			Format:    getEnvAsString("OUTPUT_FORMAT", "text"),
+			Numbering: getEnvAsString("NUMBERING", "case"),
+		},
+		TextConfig: TextConfig{
+			Normalization: getEnvAsString("NORMALIZATION", "none"),
+			FoldAccents:   getEnvAsBool("FOLD_ACCENTS", false),
+			FoldCase:      getEnvAsBool("FOLD_CASE", false),
 		},
 	}
 }
@@ -56,3 +116,21 @@ func getEnvAsInt(name string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+// utility to get an environment variable as a string.
+func getEnvAsString(name string, defaultVal string) string {
+	if value, exists := os.LookupEnv(name); exists && value != "" {
+		return value
+	}
+	return defaultVal
+}
+
+// utility to get an environment variable as a boolean.
+func getEnvAsBool(name string, defaultVal bool) bool {
+	if value, exists := os.LookupEnv(name); exists {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultVal
+}
@@ -0,0 +1,69 @@
+package diagnostics
+
+import (
+	"fmt"
+	"strings"
+)
+
+// maxValueLength is the number of characters of an offending value kept in a Diagnostic, longer values are truncated.
+const maxValueLength = 80
+
+// Rule names a constraint that dictionary words or input lines must satisfy.
+type Rule string
+
+const (
+	// RuleWordTooShort is violated by dictionary words shorter than MIN_WORD_LENGTH.
+	RuleWordTooShort Rule = "word-too-short"
+	// RuleWordTooLong is violated by dictionary words longer than MAX_WORD_LENGTH.
+	RuleWordTooLong Rule = "word-too-long"
+	// RuleDuplicateWord is violated by dictionary words listed more than once.
+	RuleDuplicateWord Rule = "duplicate-word"
+	// RuleDictionaryTooLarge is violated by the first dictionary word beyond MAX_DICTIONARY_SIZE.
+	RuleDictionaryTooLarge Rule = "dictionary-too-large"
+	// RuleUnknownFlag is violated by annotated dictionary words carrying a flag cipherlex does not understand.
+	RuleUnknownFlag Rule = "unknown-flag"
+	// RuleLineTooShort is violated by input lines shorter than MIN_LINE_LENGTH.
+	RuleLineTooShort Rule = "line-too-short"
+	// RuleLineTooLong is violated by input lines longer than MAX_LINE_LENGTH.
+	RuleLineTooLong Rule = "line-too-long"
+	// RuleTooManyLines is violated by the first input line beyond MAX_LINE_COUNT.
+	RuleTooManyLines Rule = "too-many-lines"
+)
+
+// Diagnostic reports a dictionary word or input line violating a rule.
+type Diagnostic struct {
+	File  string `json:"file,omitempty"` // file the value was read from, empty when read from a reader
+	Line  int    `json:"line"`           // line number of the value within its file, starting at 1
+	Rule  Rule   `json:"rule"`
+	Value string `json:"value"` // offending value, truncated when very long
+}
+
+// creates a new Diagnostic, truncating the given value when it is very long.
+func New(file string, line int, rule Rule, value string) Diagnostic {
+	if runes := []rune(value); len(runes) > maxValueLength {
+		value = string(runes[:maxValueLength]) + "…"
+	}
+	return Diagnostic{File: file, Line: line, Rule: rule, Value: value}
+}
+
+// String formats the diagnostic as "file:line: rule: value", in the style of compiler errors.
+func (d Diagnostic) String() string {
+	file := d.File
+	if file == "" {
+		file = "-"
+	}
+	return fmt.Sprintf("%s:%d: %s: %q", file, d.Line, d.Rule, d.Value)
+}
+
+// Error is returned by strict loaders when dictionary words or input lines violate a rule, holding every violation.
+type Error struct {
+	Diagnostics []Diagnostic
+}
+
+func (e *Error) Error() string {
+	lines := make([]string, len(e.Diagnostics))
+	for i, d := range e.Diagnostics {
+		lines[i] = d.String()
+	}
+	return fmt.Sprintf("%d validation error(s):\n%s", len(e.Diagnostics), strings.Join(lines, "\n"))
+}
@@ -0,0 +1,26 @@
+package diagnostics
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiagnostic_String(t *testing.T) {
+	assert.Equal(t, `dict.txt:3: duplicate-word: "apple"`, New("dict.txt", 3, RuleDuplicateWord, "apple").String())
+	assert.Equal(t, `-:1: line-too-short: "a"`, New("", 1, RuleLineTooShort, "a").String())
+}
+
+func TestNew_TruncatesLongValues(t *testing.T) {
+	d := New("input.txt", 1, RuleLineTooLong, strings.Repeat("é", 200))
+	assert.Equal(t, strings.Repeat("é", maxValueLength)+"…", d.Value)
+}
+
+func TestError_ListsEveryDiagnostic(t *testing.T) {
+	err := &Error{Diagnostics: []Diagnostic{
+		New("dict.txt", 1, RuleWordTooShort, "a"),
+		New("dict.txt", 2, RuleDuplicateWord, "ab"),
+	}}
+	assert.Equal(t, "2 validation error(s):\ndict.txt:1: word-too-short: \"a\"\ndict.txt:2: duplicate-word: \"ab\"", err.Error())
+}
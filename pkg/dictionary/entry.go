@@ -0,0 +1,98 @@
+package dictionary
+
+import (
+	"strings"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// MatchPolicy decides how occurrences of a single dictionary word are matched, overriding the configured match mode.
+type MatchPolicy string
+
+const (
+	// PolicyDefault matches the word according to the configured match mode.
+	PolicyDefault MatchPolicy = ""
+	// PolicyExact only matches the word as it is, unscrambled.
+	PolicyExact MatchPolicy = "exact"
+	// PolicyScrambled matches scrambles of the word, even when the configured match mode is exact.
+	PolicyScrambled MatchPolicy = "scrambled"
+)
+
+// flagIgnoreCase is the dictionary flag that makes matching of a single word case-insensitive.
+const flagIgnoreCase = "ignore-case"
+
+// Policy holds the per-word match settings of a dictionary entry.
+type Policy struct {
+	Match      MatchPolicy `json:"match,omitempty"`
+	IgnoreCase bool        `json:"ignore_case,omitempty"`
+}
+
+// Entry is a dictionary word along with its match policy, and the name of the dictionary it comes from when several are used.
+type Entry struct {
+	Word       string `json:"word"`
+	Policy     Policy `json:"policy"`
+	Dictionary string `json:"dictionary,omitempty"`
+}
+
+// utility to parse a dictionary line, either a bare word or an annotated word followed by a tab and its flags.
+// flags are separated by commas or spaces, unknown flags are logged, ignored and returned.
+func parseEntry(line string) (Entry, []string) {
+	word, flags, annotated := strings.Cut(line, "\t")
+	entry := Entry{Word: strings.TrimSpace(word)}
+	if !annotated {
+		return entry, nil
+	}
+
+	var unknownFlags []string
+	for _, flag := range strings.FieldsFunc(flags, isFlagSeparator) {
+		switch strings.ToLower(flag) {
+		case string(PolicyExact):
+			entry.Policy.Match = PolicyExact
+		case string(PolicyScrambled):
+			entry.Policy.Match = PolicyScrambled
+		case flagIgnoreCase:
+			entry.Policy.IgnoreCase = true
+		default:
+			utils.Log.WithFields(map[string]interface{}{
+				"word": entry.Word,
+				"flag": flag,
+			}).Warn("Ignoring unknown dictionary flag")
+			unknownFlags = append(unknownFlags, flag)
+		}
+	}
+	return entry, unknownFlags
+}
+
+// utility to check whether the given rune separates the flags of an annotated dictionary line.
+func isFlagSeparator(r rune) bool {
+	return r == ',' || r == ' ' || r == '\t'
+}
+
+// Words returns the words of the given entries, in order.
+func Words(entries []Entry) []string {
+	if entries == nil {
+		return nil
+	}
+	words := make([]string, len(entries))
+	for i, entry := range entries {
+		words[i] = entry.Word
+	}
+	return words
+}
+
+// Tag sets the dictionary of the given entries to the given name, returning them for convenience.
+func Tag(entries []Entry, name string) []Entry {
+	for i := range entries {
+		entries[i].Dictionary = name
+	}
+	return entries
+}
+
+// utility to wrap plain words into entries with the default policy.
+func entriesOf(words []string) []Entry {
+	entries := make([]Entry, len(words))
+	for i, word := range words {
+		entries[i] = Entry{Word: word}
+	}
+	return entries
+}
@@ -1,25 +1,43 @@
 package dictionary
 
 import (
-	"bufio"
-	"os"
-	"strings"
+	"context"
+	"fmt"
+	"io"
 
 	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
 	"github.com/1x-eng/cipherlex/pkg/utils"
 )
 
+// Diagnostic reports a dictionary word violating a constraint, along with the file and line it was read from.
+type Diagnostic = diagnostics.Diagnostic
+
 // interface for loading and filtering words from a dictionary.
 type DictionaryProcessor interface {
-	LoadDictionary(filePath string) ([]string, error)
+	LoadDictionary(ctx context.Context, filePath string) ([]string, error)
+	LoadDictionaryFrom(ctx context.Context, r io.Reader) ([]string, error)
+	LoadEntries(ctx context.Context, filePath string) ([]Entry, error)
+	LoadEntriesFrom(ctx context.Context, r io.Reader) ([]Entry, error)
+	Validate(ctx context.Context, filePath string) ([]Diagnostic, error)
+	ValidateFrom(ctx context.Context, r io.Reader) ([]Diagnostic, error)
 	ApplyConstraints(words []string) []string
 }
 
 // Processor implements the DictionaryProcessor interface.
+// dictionary lines are either bare words, or words followed by a tab and comma separated flags (exact, scrambled, ignore-case).
 type Processor struct {
 	config config.DictionaryConfig
 }
 
+// scannedEntry is a dictionary entry along with where it was read from, and the unknown flags it was annotated with.
+type scannedEntry struct {
+	Entry
+	file         string
+	line         int
+	unknownFlags []string
+}
+
 // creates a new Processor with the given configuration.
 func NewProcessor(config config.DictionaryConfig) *Processor {
 	return &Processor{
@@ -27,98 +45,217 @@ func NewProcessor(config config.DictionaryConfig) *Processor {
 	}
 }
 
-// LoadDictionary loads the dictionary from a file.
-func (p *Processor) LoadDictionary(filePath string) ([]string, error) {
+// LoadDictionary loads the dictionary words from a file, dropping their flags.
+func (p *Processor) LoadDictionary(ctx context.Context, filePath string) ([]string, error) {
+	entries, err := p.LoadEntries(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Words(entries), nil
+}
+
+// LoadDictionaryFrom loads the dictionary words from the given reader, dropping their flags.
+func (p *Processor) LoadDictionaryFrom(ctx context.Context, r io.Reader) ([]string, error) {
+	entries, err := p.LoadEntriesFrom(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return Words(entries), nil
+}
+
+// LoadEntries loads the dictionary entries, words along with their match policies, from a file.
+func (p *Processor) LoadEntries(ctx context.Context, filePath string) ([]Entry, error) {
 	utils.Log.WithFields(map[string]interface{}{
 		"filePath": filePath,
 	}).Debug("Loading dictionary from file")
 
-	words, err := p.readWordsFromFile(filePath)
+	entries, err := p.readEntriesFromFile(ctx, filePath)
 	if err != nil {
 		utils.Log.WithError(err).Error("Failed to read words from file")
 		return nil, err
 	}
 
-	filteredWords := p.ApplyConstraints(words)
+	return p.applyAndLogConstraints(entries)
+}
+
+// LoadEntriesFrom loads the dictionary entries, words along with their match policies, from the given reader.
+func (p *Processor) LoadEntriesFrom(ctx context.Context, r io.Reader) ([]Entry, error) {
+	utils.Log.Debug("Loading dictionary from reader")
+
+	entries, err := scanEntries(ctx, r, "", p.config.MaxTokenSize)
+	if err != nil {
+		return nil, err
+	}
+	return p.applyAndLogConstraints(entries)
+}
+
+// Validate reports every dictionary word of a file violating a constraint, without failing on them.
+// an error is only returned when the file cannot be read.
+func (p *Processor) Validate(ctx context.Context, filePath string) ([]Diagnostic, error) {
+	entries, err := p.readEntriesFromFile(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	_, diags := filterEntries(entries, p.config)
+	return diags, nil
+}
+
+// ValidateFrom reports every dictionary word of the given reader violating a constraint, without failing on them.
+func (p *Processor) ValidateFrom(ctx context.Context, r io.Reader) ([]Diagnostic, error) {
+	entries, err := scanEntries(ctx, r, "", p.config.MaxTokenSize)
+	if err != nil {
+		return nil, err
+	}
+	_, diags := filterEntries(entries, p.config)
+	return diags, nil
+}
+
+// applies the constraints to the given entries and logs how many were kept.
+// words violating a constraint are dropped, or fail the load with a *diagnostics.Error in strict mode.
+func (p *Processor) applyAndLogConstraints(entries []scannedEntry) ([]Entry, error) {
+	filteredEntries, diags := filterEntries(entries, p.config)
 	utils.Log.WithFields(map[string]interface{}{
-		"originalWordCount": len(words),
-		"filteredWordCount": len(filteredWords),
+		"originalWordCount": len(entries),
+		"filteredWordCount": len(filteredEntries),
 	}).Debug("Applied constraints to dictionary words")
 
-	return filteredWords, nil
+	if len(diags) > 0 {
+		if p.config.Strict {
+			return nil, &diagnostics.Error{Diagnostics: diags}
+		}
+		utils.Log.WithField("violationCount", len(diags)).Warn("Dropped or ignored dictionary words violating constraints, run validate to list them")
+	}
+	return filteredEntries, nil
 }
 
-// utility to scan words from given file into a slice.
-func scanWords(file *os.File) []string {
-	scanner := bufio.NewScanner(file)
-	var words []string
-	for scanner.Scan() {
-		words = append(words, strings.TrimSpace(scanner.Text()))
+// utility to scan entries from given reader into a slice, stopping once the given context is done.
+// entries remember the given file name and their line number. read errors, including lines longer than maxTokenSize bytes, are returned.
+func scanEntries(ctx context.Context, r io.Reader, file string, maxTokenSize int) ([]scannedEntry, error) {
+	scanner := utils.NewScanner(r, maxTokenSize)
+	var entries []scannedEntry
+	line := 1
+	for ; scanner.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		entry, unknownFlags := parseEntry(scanner.Text())
+		entries = append(entries, scannedEntry{Entry: entry, file: file, line: line, unknownFlags: unknownFlags})
+	}
+	if err := utils.ScanError(scanner, line, maxTokenSize); err != nil {
+		return nil, err
 	}
-	return words
+	return entries, nil
 }
 
-// readWordsFromFile reads words from the given file path.
-func (p *Processor) readWordsFromFile(filePath string) ([]string, error) {
-	file, err := os.Open(filePath)
+// readEntriesFromFile reads entries from the given file path, which may also be a directory or a glob pattern of plain or compressed files.
+// files are read one at a time, so that every entry knows the file and line it was read from.
+func (p *Processor) readEntriesFromFile(ctx context.Context, filePath string) ([]scannedEntry, error) {
+	files, err := utils.ExpandPaths(filePath)
 	if err != nil {
 		utils.Log.WithError(err).WithField("filePath", filePath).Error("Failed to open file")
 		return nil, err
 	}
-	defer file.Close()
 
-	words := scanWords(file)
+	var entries []scannedEntry
+	for _, name := range files {
+		fileEntries, err := readEntriesFromSingleFile(ctx, name, p.config.MaxTokenSize)
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, fileEntries...)
+	}
 	utils.Log.WithFields(map[string]interface{}{
 		"filePath":  filePath,
-		"wordCount": len(words),
+		"fileCount": len(files),
+		"wordCount": len(entries),
 	}).Debug("Scanned words from file")
 
-	return words, nil
+	return entries, nil
 }
 
-// isValidWord is a utility to check if the given word is valid according to the configuration.
-func isValidWord(word string, config config.DictionaryConfig) bool {
-	isValid := len(word) >= config.MinWordLength && len(word) <= config.MaxWordLength
+// utility to read entries from a single plain or compressed file.
+func readEntriesFromSingleFile(ctx context.Context, name string, maxTokenSize int) ([]scannedEntry, error) {
+	file, err := utils.OpenFile(name)
+	if err != nil {
+		utils.Log.WithError(err).WithField("filePath", name).Error("Failed to open file")
+		return nil, err
+	}
+	defer file.Close()
+
+	entries, err := scanEntries(ctx, file, name, maxTokenSize)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", name, err)
+	}
+	return entries, nil
+}
+
+// checkWord is a utility to check the given word against the length constraints of the configuration, returning the violated rule if any.
+// the length of a word is measured in user-perceived characters, not bytes.
+func checkWord(word string, config config.DictionaryConfig) diagnostics.Rule {
+	var rule diagnostics.Rule
+	switch length := utils.GraphemeCount(word); {
+	case length < config.MinWordLength:
+		rule = diagnostics.RuleWordTooShort
+	case length > config.MaxWordLength:
+		rule = diagnostics.RuleWordTooLong
+	}
 
-	if !isValid {
+	if rule != "" {
 		utils.Log.WithFields(map[string]interface{}{
 			"word": word,
+			"rule": rule,
 		}).Debug("Invalid word")
 	}
 
-	return isValid
+	return rule
 }
 
-// filterWords filters the given words according to the configuration.
-func filterWords(words []string, config config.DictionaryConfig) []string {
-	var filteredWords []string
+// filterEntries filters the given entries according to the configuration, the first entry of a repeated word wins.
+// every violation is reported as a diagnostic, except for blank lines which are skipped silently.
+// once the maximum dictionary size is reached, the first word beyond it is reported and the remaining entries are not looked at.
+func filterEntries(entries []scannedEntry, config config.DictionaryConfig) ([]Entry, []Diagnostic) {
+	var filteredEntries []Entry
+	var diags []Diagnostic
 	wordSet := make(map[string]struct{})
 
-	for _, word := range words {
-		if !isValidWord(word, config) {
+	for _, entry := range entries {
+		if rule := checkWord(entry.Word, config); rule != "" {
+			if entry.Word != "" {
+				diags = append(diags, diagnostics.New(entry.file, entry.line, rule, entry.Word))
+			}
 			continue
 		}
-		if _, exists := wordSet[word]; exists {
+		for _, flag := range entry.unknownFlags {
+			diags = append(diags, diagnostics.New(entry.file, entry.line, diagnostics.RuleUnknownFlag, flag))
+		}
+		if _, exists := wordSet[entry.Word]; exists {
+			diags = append(diags, diagnostics.New(entry.file, entry.line, diagnostics.RuleDuplicateWord, entry.Word))
 			continue
 		}
-		if len(filteredWords) >= config.MaxDictionarySize {
+		if len(filteredEntries) >= config.MaxDictionarySize {
 
 			utils.Log.WithFields(map[string]interface{}{
 				"maxDictionarySize": config.MaxDictionarySize,
-				"wordCount":         len(filteredWords),
-				"filteredWords":     filteredWords,
+				"wordCount":         len(filteredEntries),
+				"filteredWords":     Words(filteredEntries),
 			}).Warn("Reached max dictionary size, will not process any more words")
 
+			diags = append(diags, diagnostics.New(entry.file, entry.line, diagnostics.RuleDictionaryTooLarge, entry.Word))
 			break
 		}
-		filteredWords = append(filteredWords, word)
-		wordSet[word] = struct{}{}
+		filteredEntries = append(filteredEntries, entry.Entry)
+		wordSet[entry.Word] = struct{}{}
 	}
 
-	return filteredWords
+	return filteredEntries, diags
 }
 
 // ApplyConstraints applies the constraints to the given words.
 func (p *Processor) ApplyConstraints(words []string) []string {
-	return filterWords(words, p.config)
+	entries := make([]scannedEntry, len(words))
+	for i, entry := range entriesOf(words) {
+		entries[i] = scannedEntry{Entry: entry, line: i + 1}
+	}
+	filteredEntries, _ := filterEntries(entries, p.config)
+	return Words(filteredEntries)
 }
@@ -1,9 +1,13 @@
 package dictionary
 
 import (
+	"bufio"
+	"context"
+	"strings"
 	"testing"
 
 	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,7 +19,7 @@ func TestLoadDictionary_Success(t *testing.T) {
 		MaxDictionarySize: 100,
 	})
 
-	words, err := processor.LoadDictionary("../../test_data/dict.txt")
+	words, err := processor.LoadDictionary(context.Background(), "../../test_data/dict.txt")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, words, "Dictionary should not be empty")
 }
@@ -28,7 +32,7 @@ func TestLoadDictionary_WordConstraints(t *testing.T) {
 		MaxDictionarySize: 100,
 	})
 
-	words, err := processor.LoadDictionary("../../test_data/dict.txt")
+	words, err := processor.LoadDictionary(context.Background(), "../../test_data/dict.txt")
 	assert.NoError(t, err)
 
 	for _, word := range words {
@@ -45,7 +49,7 @@ func TestLoadDictionary_DuplicateWords(t *testing.T) {
 		MaxDictionarySize: 100,
 	})
 
-	words, err := processor.LoadDictionary("../../test_data/dict_invalid.txt")
+	words, err := processor.LoadDictionary(context.Background(), "../../test_data/dict_invalid.txt")
 	assert.NoError(t, err)
 
 	wordSet := make(map[string]bool)
@@ -64,7 +68,126 @@ func TestLoadDictionary_MaxSize(t *testing.T) {
 		MaxDictionarySize: maxSize,
 	})
 
-	words, err := processor.LoadDictionary("../../test_data/dict_invalid.txt")
+	words, err := processor.LoadDictionary(context.Background(), "../../test_data/dict_invalid.txt")
 	assert.NoError(t, err)
 	assert.Len(t, words, maxSize, "The number of loaded words should not exceed the maximum size")
 }
+
+// TestLoadDictionary_Canceled checks that loading stops once the context is done.
+func TestLoadDictionary_Canceled(t *testing.T) {
+	processor := NewProcessor(config.DictionaryConfig{
+		MinWordLength:     2,
+		MaxWordLength:     5,
+		MaxDictionarySize: 100,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := processor.LoadDictionary(ctx, "../../test_data/dict.txt")
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
+// TestLoadDictionary_CharacterLength checks that word lengths are measured in characters rather than bytes.
+func TestLoadDictionary_CharacterLength(t *testing.T) {
+	processor := NewProcessor(config.DictionaryConfig{
+		MinWordLength:     3,
+		MaxWordLength:     4,
+		MaxDictionarySize: 100,
+	})
+
+	words, err := processor.LoadDictionaryFrom(context.Background(), strings.NewReader("λόγος\nмир\nことば\ncafé\nab\n"))
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"мир", "ことば", "café"}, words)
+}
+
+// TestLoadEntries_Annotated checks that annotated lines carry their policies while bare words keep the default one.
+func TestLoadEntries_Annotated(t *testing.T) {
+	processor := NewProcessor(config.DictionaryConfig{
+		MinWordLength:     2,
+		MaxWordLength:     10,
+		MaxDictionarySize: 100,
+	})
+
+	dict := "apple\texact\nBerry\tscrambled, ignore-case\ncherry\nplum\tbogus\napple\tscrambled\n"
+	entries, err := processor.LoadEntriesFrom(context.Background(), strings.NewReader(dict))
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Word: "apple", Policy: Policy{Match: PolicyExact}},
+		{Word: "Berry", Policy: Policy{Match: PolicyScrambled, IgnoreCase: true}},
+		{Word: "cherry"},
+		{Word: "plum"},
+	}, entries)
+
+	words, err := processor.LoadDictionaryFrom(context.Background(), strings.NewReader(dict))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"apple", "Berry", "cherry", "plum"}, words)
+}
+
+// TestLoadDictionary_CompressedAndGlob checks that compressed files and glob patterns are read transparently.
+func TestLoadDictionary_CompressedAndGlob(t *testing.T) {
+	processor := NewProcessor(config.DictionaryConfig{
+		MinWordLength:     2,
+		MaxWordLength:     20,
+		MaxDictionarySize: 100,
+	})
+
+	words, err := processor.LoadDictionary(context.Background(), "../../test_data/compressed/*.bz2")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"axpaj", "dnrbt"}, words)
+}
+
+// TestValidate_ReportsViolations checks that every violation is reported with its file, line, rule and value.
+func TestValidate_ReportsViolations(t *testing.T) {
+	processor := NewProcessor(config.DictionaryConfig{
+		MinWordLength:     2,
+		MaxWordLength:     5,
+		MaxDictionarySize: 3,
+	})
+
+	diags, err := processor.ValidateFrom(context.Background(), strings.NewReader("apple\na\n\napple\nplum\tbogus\nbanana\nfig\nkiwi\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{Line: 2, Rule: diagnostics.RuleWordTooShort, Value: "a"},
+		{Line: 4, Rule: diagnostics.RuleDuplicateWord, Value: "apple"},
+		{Line: 5, Rule: diagnostics.RuleUnknownFlag, Value: "bogus"},
+		{Line: 6, Rule: diagnostics.RuleWordTooLong, Value: "banana"},
+		{Line: 8, Rule: diagnostics.RuleDictionaryTooLarge, Value: "kiwi"},
+	}, diags)
+
+	diags, err = processor.Validate(context.Background(), "../../test_data/dict_invalid.txt")
+	assert.NoError(t, err)
+	assert.Equal(t, "../../test_data/dict_invalid.txt", diags[0].File)
+	assert.Equal(t, 2, diags[0].Line)
+}
+
+// TestLoadDictionary_Strict checks that strict mode fails on violations rather than dropping them.
+func TestLoadDictionary_Strict(t *testing.T) {
+	processor := NewProcessor(config.DictionaryConfig{
+		MinWordLength:     2,
+		MaxWordLength:     10,
+		MaxDictionarySize: 100,
+		Strict:            true,
+	})
+
+	words, err := processor.LoadDictionaryFrom(context.Background(), strings.NewReader("apple\n\nberry\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"apple", "berry"}, words, "Blank lines should not be violations")
+
+	_, err = processor.LoadDictionaryFrom(context.Background(), strings.NewReader("apple\napple\n"))
+	var diagErr *diagnostics.Error
+	assert.ErrorAs(t, err, &diagErr)
+	assert.Equal(t, []Diagnostic{{Line: 2, Rule: diagnostics.RuleDuplicateWord, Value: "apple"}}, diagErr.Diagnostics)
+}
+
+// TestLoadDictionary_TokenTooLong checks that a word over MaxTokenSize fails the load rather than ending the dictionary early.
+func TestLoadDictionary_TokenTooLong(t *testing.T) {
+	processor := NewProcessor(config.DictionaryConfig{
+		MinWordLength:     2,
+		MaxWordLength:     10,
+		MaxDictionarySize: 100,
+		MaxTokenSize:      16,
+	})
+
+	_, err := processor.LoadDictionaryFrom(context.Background(), strings.NewReader("apple\n"+strings.Repeat("x", 32)+"\nberry\n"))
+	assert.ErrorIs(t, err, bufio.ErrTooLong)
+}
@@ -0,0 +1,136 @@
+package index
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// encoder appends values to a buffer in the binary layout of index files.
+// integers are varints, strings and byte slices are prefixed with their length.
+type encoder struct {
+	buf []byte
+}
+
+// appends an unsigned integer.
+func (e *encoder) uvarint(v uint64) {
+	e.buf = binary.AppendUvarint(e.buf, v)
+}
+
+// appends a signed integer.
+func (e *encoder) varint(v int64) {
+	e.buf = binary.AppendVarint(e.buf, v)
+}
+
+// appends a non-negative int.
+func (e *encoder) int(v int) {
+	e.uvarint(uint64(v))
+}
+
+// appends a boolean as a single byte.
+func (e *encoder) bool(v bool) {
+	if v {
+		e.buf = append(e.buf, 1)
+	} else {
+		e.buf = append(e.buf, 0)
+	}
+}
+
+// appends a string, prefixed with its length in bytes.
+func (e *encoder) string(s string) {
+	e.int(len(s))
+	e.buf = append(e.buf, s...)
+}
+
+// appends raw bytes, without a length prefix.
+func (e *encoder) raw(b []byte) {
+	e.buf = append(e.buf, b...)
+}
+
+// decoder reads values written by an encoder from a buffer.
+// the first error is kept and every later read returns a zero value, so callers only check err once they are done.
+type decoder struct {
+	buf []byte
+	err error
+}
+
+// utility to record the first error, and stop reading.
+func (d *decoder) fail(format string, args ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, args...)
+	}
+	d.buf = nil
+}
+
+// reads an unsigned integer.
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf)
+	if n <= 0 {
+		d.fail("malformed unsigned integer")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// reads a signed integer.
+func (d *decoder) varint() int64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(d.buf)
+	if n <= 0 {
+		d.fail("malformed integer")
+		return 0
+	}
+	d.buf = d.buf[n:]
+	return v
+}
+
+// reads a non-negative int that must not exceed the given limit, such as the number of bytes left for a length.
+func (d *decoder) int(limit int) int {
+	v := d.uvarint()
+	if v > uint64(limit) {
+		d.fail("value %d exceeds %d", v, limit)
+		return 0
+	}
+	return int(v)
+}
+
+// reads a boolean.
+func (d *decoder) bool() bool {
+	b := d.raw(1)
+	if b == nil {
+		return false
+	}
+	switch b[0] {
+	case 0:
+		return false
+	case 1:
+		return true
+	default:
+		d.fail("malformed boolean %d", b[0])
+		return false
+	}
+}
+
+// reads a string.
+func (d *decoder) string() string {
+	return string(d.raw(d.int(len(d.buf))))
+}
+
+// reads the given number of raw bytes.
+func (d *decoder) raw(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n > len(d.buf) {
+		d.fail("unexpected end of data, %d bytes needed but %d left", n, len(d.buf))
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
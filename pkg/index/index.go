@@ -0,0 +1,398 @@
+// Package index compiles dictionaries into a versioned binary file, so that runs over large dictionaries skip loading,
+// validating and normalizing them. an index remembers the files it was compiled from and the settings it depends on,
+// and is rejected once either changes.
+package index
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/utils"
+	"github.com/1x-eng/cipherlex/pkg/wordmatcher"
+)
+
+// Magic starts every index file.
+const Magic = "CLXINDEX"
+
+// Version is the version of the binary layout written by this package, files of any other version are rejected.
+const Version uint16 = 2
+
+var (
+	// ErrCorrupt is returned when an index file is truncated, fails its checksum, or is not an index file at all.
+	ErrCorrupt = errors.New("corrupt index")
+	// ErrVersion is returned when an index file was written with another version of the binary layout.
+	ErrVersion = errors.New("unsupported index version")
+	// ErrStale is returned when the dictionaries an index was compiled from, or the settings it depends on, changed since.
+	ErrStale = errors.New("stale index")
+)
+
+// Settings are the configuration settings the words of an index depend on, an index only serves runs with the same settings.
+// Strict and MaxTokenSize decide which dictionary lines are dropped or fail the compilation, so an index compiled under other values
+// would skip the diagnostics a fresh load reports.
+type Settings struct {
+	Mode              string
+	Normalization     string
+	FoldAccents       bool
+	FoldCase          bool
+	IgnorePunctuation bool
+	MinWordLength     int
+	MaxWordLength     int
+	MaxDictionarySize int
+	MaxTokenSize      int
+	Strict            bool
+}
+
+// SettingsOf returns the settings of the given configuration an index depends on, with default mode and normalization spelled out.
+func SettingsOf(cfg config.AppConfig) Settings {
+	settings := Settings{
+		Mode:              cfg.Mode,
+		Normalization:     cfg.Normalization,
+		FoldAccents:       cfg.FoldAccents,
+		FoldCase:          cfg.FoldCase,
+		IgnorePunctuation: cfg.IgnorePunctuation,
+		MinWordLength:     cfg.MinWordLength,
+		MaxWordLength:     cfg.MaxWordLength,
+		MaxDictionarySize: cfg.MaxDictionarySize,
+		MaxTokenSize:      cfg.DictionaryConfig.MaxTokenSize,
+		Strict:            cfg.DictionaryConfig.Strict,
+	}
+	if mode, err := wordmatcher.ParseMode(cfg.Mode); err == nil {
+		settings.Mode = string(mode)
+	}
+	if normalization, err := utils.ParseNormalization(cfg.Normalization); err == nil {
+		settings.Normalization = string(normalization)
+	}
+	return settings
+}
+
+// Dictionary is a dictionary an index was compiled from, its name is empty unless several named dictionaries were compiled.
+type Dictionary struct {
+	Name string
+	Path string
+}
+
+// SourceFile fingerprints a file a dictionary was read from.
+type SourceFile struct {
+	Path    string
+	Size    int64
+	ModTime time.Time
+	SHA256  [sha256.Size]byte
+}
+
+// Index holds the compiled words of one or more dictionaries, along with the dictionaries, files and settings they were compiled from.
+type Index struct {
+	Settings     Settings
+	Dictionaries []Dictionary
+	Files        []SourceFile
+	Words        []wordmatcher.CompiledWord
+}
+
+// New creates an Index of the given words, compiled from the given dictionaries with the given settings.
+// every file of every dictionary is fingerprinted, so dictionaries must be paths to files, directories or glob patterns.
+// paths are stored absolute and cleaned, so the index serves runs from any working directory.
+func New(settings Settings, dictionaries []Dictionary, words []wordmatcher.CompiledWord) (*Index, error) {
+	ix := &Index{Settings: settings, Words: words}
+	for _, dict := range dictionaries {
+		path, err := filepath.Abs(dict.Path)
+		if err != nil {
+			return nil, err
+		}
+		ix.Dictionaries = append(ix.Dictionaries, Dictionary{Name: dict.Name, Path: path})
+
+		files, err := utils.ExpandPaths(path)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			file, err := fingerprint(path)
+			if err != nil {
+				return nil, err
+			}
+			ix.Files = append(ix.Files, file)
+		}
+	}
+	return ix, nil
+}
+
+// utility to fingerprint the file at the given path.
+func fingerprint(path string) (SourceFile, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return SourceFile{}, err
+	}
+	sum, err := hashFile(path)
+	if err != nil {
+		return SourceFile{}, err
+	}
+	return SourceFile{Path: path, Size: info.Size(), ModTime: info.ModTime(), SHA256: sum}, nil
+}
+
+// utility to compute the SHA-256 checksum of the raw content of the file at the given path.
+func hashFile(path string) ([sha256.Size]byte, error) {
+	var sum [sha256.Size]byte
+	f, err := os.Open(path)
+	if err != nil {
+		return sum, err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return sum, err
+	}
+	copy(sum[:], h.Sum(nil))
+	return sum, nil
+}
+
+// Check returns an error wrapping ErrStale if the index cannot serve a run with the given configuration,
+// because it was compiled with other settings or because a dictionary file was added, removed or changed since.
+// files whose size and modification time are unchanged are trusted, others are hashed again and only rejected if their content differs.
+func (ix *Index) Check(cfg config.AppConfig) error {
+	if settings := SettingsOf(cfg); settings != ix.Settings {
+		return fmt.Errorf("%w: compiled with settings %+v, but the run uses %+v", ErrStale, ix.Settings, settings)
+	}
+
+	var paths []string
+	for _, dict := range ix.Dictionaries {
+		path, err := filepath.Abs(dict.Path)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrStale, err)
+		}
+		files, err := utils.ExpandPaths(path)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrStale, err)
+		}
+		paths = append(paths, files...)
+	}
+	if len(paths) != len(ix.Files) {
+		return fmt.Errorf("%w: compiled from %d dictionary files, but %d are present now", ErrStale, len(ix.Files), len(paths))
+	}
+	for i, file := range ix.Files {
+		if paths[i] != file.Path {
+			return fmt.Errorf("%w: dictionary file %q is not one the index was compiled from", ErrStale, paths[i])
+		}
+		info, err := os.Stat(file.Path)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrStale, err)
+		}
+		if info.Size() == file.Size && info.ModTime().Equal(file.ModTime) {
+			continue
+		}
+		sum, err := hashFile(file.Path)
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrStale, err)
+		}
+		if sum != file.SHA256 {
+			return fmt.Errorf("%w: dictionary file %q changed since the index was compiled", ErrStale, file.Path)
+		}
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"dictionaryCount": len(ix.Dictionaries),
+		"fileCount":       len(ix.Files),
+	}).Debug("Checked index against its dictionaries")
+
+	return nil
+}
+
+// WriteTo writes the index to the given writer in the binary layout of index files.
+// the layout is Magic, Version as two little endian bytes, the encoded index, and a CRC-32 checksum of everything before it.
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	e := &encoder{}
+	e.raw([]byte(Magic))
+	e.raw([]byte{byte(Version), byte(Version >> 8)})
+	ix.encode(e)
+	checksum := crc32.ChecksumIEEE(e.buf)
+	e.raw([]byte{byte(checksum), byte(checksum >> 8), byte(checksum >> 16), byte(checksum >> 24)})
+
+	n, err := w.Write(e.buf)
+	return int64(n), err
+}
+
+// WriteFile writes the index to the file at the given path, replacing it at once so that readers never see a partial index.
+func (ix *Index) WriteFile(path string) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := ix.WriteTo(tmp); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0o644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// Read reads an index written by WriteTo from the given reader.
+// it returns an error wrapping ErrCorrupt or ErrVersion when the data is not a valid index of the current version.
+func Read(r io.Reader) (*Index, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return decodeIndex(data)
+}
+
+// Load reads the index file at the given path, see Read.
+func Load(path string) (*Index, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := decodeIndex(data)
+	if err != nil {
+		return nil, err
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"path":      path,
+		"size":      len(data),
+		"wordCount": len(ix.Words),
+	}).Debug("Loaded index")
+
+	return ix, nil
+}
+
+// utility to decode a whole index file, checking its magic, version and checksum before anything else.
+func decodeIndex(data []byte) (*Index, error) {
+	header := len(Magic) + 2
+	if len(data) < header+crc32.Size || !bytes.Equal(data[:len(Magic)], []byte(Magic)) {
+		return nil, fmt.Errorf("%w: not an index file", ErrCorrupt)
+	}
+	if version := uint16(data[len(Magic)]) | uint16(data[len(Magic)+1])<<8; version != Version {
+		return nil, fmt.Errorf("%w: file has version %d, expected %d, compile the index again", ErrVersion, version, Version)
+	}
+	body, trailer := data[:len(data)-crc32.Size], data[len(data)-crc32.Size:]
+	checksum := uint32(trailer[0]) | uint32(trailer[1])<<8 | uint32(trailer[2])<<16 | uint32(trailer[3])<<24
+	if crc32.ChecksumIEEE(body) != checksum {
+		return nil, fmt.Errorf("%w: checksum mismatch", ErrCorrupt)
+	}
+
+	d := &decoder{buf: body[header:]}
+	ix := decode(d)
+	if d.err == nil && len(d.buf) > 0 {
+		d.fail("%d unexpected trailing bytes", len(d.buf))
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorrupt, d.err)
+	}
+	return ix, nil
+}
+
+// utility to encode the settings, dictionaries, files and words of the index.
+func (ix *Index) encode(e *encoder) {
+	s := ix.Settings
+	e.string(s.Mode)
+	e.string(s.Normalization)
+	e.bool(s.FoldAccents)
+	e.bool(s.FoldCase)
+	e.bool(s.IgnorePunctuation)
+	e.int(s.MinWordLength)
+	e.int(s.MaxWordLength)
+	e.int(s.MaxDictionarySize)
+	e.int(s.MaxTokenSize)
+	e.bool(s.Strict)
+
+	e.int(len(ix.Dictionaries))
+	for _, dict := range ix.Dictionaries {
+		e.string(dict.Name)
+		e.string(dict.Path)
+	}
+
+	e.int(len(ix.Files))
+	for _, file := range ix.Files {
+		e.string(file.Path)
+		e.varint(file.Size)
+		e.varint(file.ModTime.UnixNano())
+		e.raw(file.SHA256[:])
+	}
+
+	e.int(len(ix.Words))
+	for _, w := range ix.Words {
+		e.string(w.Entry.Word)
+		e.string(string(w.Entry.Policy.Match))
+		e.bool(w.Entry.Policy.IgnoreCase)
+		e.string(w.Entry.Dictionary)
+		e.string(string(w.Mode))
+		e.string(w.Normalized)
+		e.string(w.Key)
+		e.int(len(w.Graphemes))
+		for _, g := range w.Graphemes {
+			e.int(g.Offset)
+			e.int(len(g.Text))
+		}
+	}
+}
+
+// utility to decode what encode wrote, graphemes are sliced from their normalized word rather than copied.
+func decode(d *decoder) *Index {
+	ix := &Index{}
+	s := &ix.Settings
+	s.Mode = d.string()
+	s.Normalization = d.string()
+	s.FoldAccents = d.bool()
+	s.FoldCase = d.bool()
+	s.IgnorePunctuation = d.bool()
+	s.MinWordLength = d.int(maxInt)
+	s.MaxWordLength = d.int(maxInt)
+	s.MaxDictionarySize = d.int(maxInt)
+	s.MaxTokenSize = d.int(maxInt)
+	s.Strict = d.bool()
+
+	// every element takes at least one byte, which bounds counts before anything is allocated.
+	ix.Dictionaries = make([]Dictionary, d.int(len(d.buf)))
+	for i := range ix.Dictionaries {
+		ix.Dictionaries[i] = Dictionary{Name: d.string(), Path: d.string()}
+	}
+
+	ix.Files = make([]SourceFile, d.int(len(d.buf)))
+	for i := range ix.Files {
+		file := &ix.Files[i]
+		file.Path = d.string()
+		file.Size = d.varint()
+		file.ModTime = time.Unix(0, d.varint())
+		copy(file.SHA256[:], d.raw(sha256.Size))
+	}
+
+	ix.Words = make([]wordmatcher.CompiledWord, d.int(len(d.buf)))
+	for i := range ix.Words {
+		w := &ix.Words[i]
+		w.Entry = dictionary.Entry{Word: d.string()}
+		w.Entry.Policy.Match = dictionary.MatchPolicy(d.string())
+		w.Entry.Policy.IgnoreCase = d.bool()
+		w.Entry.Dictionary = d.string()
+		w.Mode = wordmatcher.Mode(d.string())
+		w.Normalized = d.string()
+		w.Key = d.string()
+		w.Graphemes = make([]utils.Grapheme, d.int(len(d.buf)))
+		for j := range w.Graphemes {
+			offset := d.int(len(w.Normalized))
+			length := d.int(len(w.Normalized) - offset)
+			w.Graphemes[j] = utils.Grapheme{Offset: offset, Text: w.Normalized[offset : offset+length]}
+		}
+		if d.err != nil {
+			break
+		}
+	}
+	return ix
+}
+
+// maxInt is the largest value of an int.
+const maxInt = int(^uint(0) >> 1)
@@ -0,0 +1,128 @@
+package index
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/wordmatcher"
+	"github.com/stretchr/testify/assert"
+)
+
+// utility to compile an index of a dictionary file holding the given content, written to a temporary directory.
+func newTestIndex(t *testing.T, content string) (*Index, string, config.AppConfig) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "dict.txt")
+	assert.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: "anagram"}}
+	entries := []dictionary.Entry{
+		{Word: "Axpaj", Policy: dictionary.Policy{IgnoreCase: true}},
+		{Word: "café", Policy: dictionary.Policy{Match: dictionary.PolicyExact}},
+	}
+	ix, err := New(SettingsOf(cfg), []Dictionary{{Path: path}}, wordmatcher.CompileEntries(entries, cfg))
+	assert.NoError(t, err)
+	return ix, path, cfg
+}
+
+func TestIndex_RoundTrip(t *testing.T) {
+	ix, _, cfg := newTestIndex(t, "Axpaj\tignore-case\ncafé\texact\n")
+
+	var buf bytes.Buffer
+	_, err := ix.WriteTo(&buf)
+	assert.NoError(t, err)
+	read, err := Read(&buf)
+	assert.NoError(t, err)
+
+	assert.Equal(t, ix.Settings, read.Settings)
+	assert.Equal(t, ix.Dictionaries, read.Dictionaries)
+	assert.Equal(t, ix.Words, read.Words)
+	assert.Len(t, read.Files, 1)
+	assert.Equal(t, ix.Files[0].SHA256, read.Files[0].SHA256)
+	assert.True(t, ix.Files[0].ModTime.Equal(read.Files[0].ModTime))
+	assert.NoError(t, read.Check(cfg))
+}
+
+func TestIndex_WriteFileAndLoad(t *testing.T) {
+	ix, path, cfg := newTestIndex(t, "Axpaj\ncafé\n")
+	indexPath := filepath.Join(filepath.Dir(path), "dict.idx")
+
+	assert.NoError(t, ix.WriteFile(indexPath))
+	loaded, err := Load(indexPath)
+	assert.NoError(t, err)
+	assert.Equal(t, ix.Words, loaded.Words)
+	assert.NoError(t, loaded.Check(cfg))
+}
+
+func TestRead_RejectsCorruptIndexes(t *testing.T) {
+	ix, _, _ := newTestIndex(t, "Axpaj\ncafé\n")
+	var buf bytes.Buffer
+	_, err := ix.WriteTo(&buf)
+	assert.NoError(t, err)
+	data := buf.Bytes()
+
+	flipped := append([]byte(nil), data...)
+	flipped[len(Magic)+5] ^= 0xff
+	_, err = Read(bytes.NewReader(flipped))
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = Read(bytes.NewReader(data[:len(data)/2]))
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	_, err = Read(bytes.NewReader([]byte("axpaj\ndnrbt\n")))
+	assert.ErrorIs(t, err, ErrCorrupt)
+
+	otherVersion := append([]byte(nil), data...)
+	otherVersion[len(Magic)]++
+	_, err = Read(bytes.NewReader(otherVersion))
+	assert.ErrorIs(t, err, ErrVersion)
+}
+
+func TestIndex_CheckRejectsStaleIndexes(t *testing.T) {
+	ix, path, cfg := newTestIndex(t, "Axpaj\ncafé\n")
+
+	exact := cfg
+	exact.Mode = "exact"
+	assert.ErrorIs(t, ix.Check(exact), ErrStale, "Other settings make the index stale")
+	strict := cfg
+	strict.DictionaryConfig.Strict = !cfg.DictionaryConfig.Strict
+	assert.ErrorIs(t, ix.Check(strict), ErrStale, "Another strictness makes the index stale")
+	tokenSize := cfg
+	tokenSize.DictionaryConfig.MaxTokenSize = cfg.DictionaryConfig.MaxTokenSize + 1
+	assert.ErrorIs(t, ix.Check(tokenSize), ErrStale, "Another maximum token size makes the index stale")
+
+	// touching the file without changing it keeps the index fresh, its content is hashed again.
+	later := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(path, later, later))
+	assert.NoError(t, ix.Check(cfg))
+
+	assert.NoError(t, os.WriteFile(path, []byte("Axpaj\ncafe\n"), 0o644))
+	assert.ErrorIs(t, ix.Check(cfg), ErrStale, "A changed dictionary file makes the index stale")
+
+	assert.NoError(t, os.Remove(path))
+	assert.ErrorIs(t, ix.Check(cfg), ErrStale, "A missing dictionary file makes the index stale")
+}
+
+func TestIndex_StoresAbsolutePaths(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "dict.txt"), []byte("axpaj\n"), 0o644))
+	wd, err := os.Getwd()
+	assert.NoError(t, err)
+	assert.NoError(t, os.Chdir(dir))
+	defer os.Chdir(wd)
+	dir, err = os.Getwd()
+	assert.NoError(t, err)
+
+	cfg := config.AppConfig{}
+	ix, err := New(SettingsOf(cfg), []Dictionary{{Path: "./sub/../dict.txt"}}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []Dictionary{{Path: filepath.Join(dir, "dict.txt")}}, ix.Dictionaries)
+	assert.Equal(t, filepath.Join(dir, "dict.txt"), ix.Files[0].Path)
+
+	assert.NoError(t, os.Chdir(wd))
+	assert.NoError(t, ix.Check(cfg), "The index serves runs from another working directory")
+}
@@ -0,0 +1,55 @@
+package input
+
+import (
+	"fmt"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// LongLinePolicy decides what happens to input lines longer than MaxLineLength.
+type LongLinePolicy string
+
+const (
+	// LongLineReject drops lines longer than MaxLineLength, reporting them as line-too-long.
+	LongLineReject LongLinePolicy = "reject"
+	// LongLineTruncate keeps the first MaxLineLength characters of a long line and drops the rest.
+	LongLineTruncate LongLinePolicy = "truncate"
+	// LongLineSplit splits a long line into consecutive lines of MaxLineLength characters, words spanning two of them are not matched.
+	LongLineSplit LongLinePolicy = "split"
+)
+
+// ParseLongLinePolicy converts the given name into a LongLinePolicy, an empty name selects LongLineReject.
+func ParseLongLinePolicy(name string) (LongLinePolicy, error) {
+	switch LongLinePolicy(name) {
+	case "", LongLineReject:
+		return LongLineReject, nil
+	case LongLineTruncate:
+		return LongLineTruncate, nil
+	case LongLineSplit:
+		return LongLineSplit, nil
+	default:
+		return "", fmt.Errorf("unknown long line policy %q, expected one of: %s, %s, %s", name, LongLineReject, LongLineTruncate, LongLineSplit)
+	}
+}
+
+// utility to split a line into pieces of at most maxLength characters each, in order.
+// when punctuation and whitespace are ignored they do not count towards the length, and stay with the characters before them.
+func splitLine(line string, maxLength int, ignorePunctuation bool) []string {
+	if maxLength <= 0 {
+		return []string{line}
+	}
+
+	var pieces []string
+	start, length := 0, 0
+	for _, g := range utils.Graphemes(line) {
+		counted := !ignorePunctuation || !g.IsPunctuationOrSpace()
+		if counted && length == maxLength {
+			pieces = append(pieces, line[start:g.Offset])
+			start, length = g.Offset, 0
+		}
+		if counted {
+			length++
+		}
+	}
+	return append(pieces, line[start:])
+}
@@ -1,17 +1,36 @@
 package input
 
 import (
-	"bufio"
-	"os"
+	"context"
+	"fmt"
+	"io"
 	"strings"
 
 	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
 	"github.com/1x-eng/cipherlex/pkg/utils"
 )
 
+// Diagnostic reports an input line violating a constraint, along with the file and line it was read from.
+type Diagnostic = diagnostics.Diagnostic
+
+// Line is an input line along with its line number in the input, counted across every file of a directory or glob pattern.
+// a line violating a constraint is skipped rather than loaded, and carries the rule it violates.
+// the pieces of a split long line share the line number of the long line.
+type Line struct {
+	Number  int
+	Text    string
+	Skipped diagnostics.Rule
+}
+
 // interface for loading and validating input strings.
 type InputProcessor interface {
-	LoadInputs(filePath string) ([]string, error)
+	LoadInputs(ctx context.Context, filePath string) ([]string, error)
+	LoadInputsFrom(ctx context.Context, r io.Reader) ([]string, error)
+	LoadLines(ctx context.Context, filePath string) ([]Line, error)
+	LoadLinesFrom(ctx context.Context, r io.Reader) ([]Line, error)
+	Validate(ctx context.Context, filePath string) ([]Diagnostic, error)
+	ValidateFrom(ctx context.Context, r io.Reader) ([]Diagnostic, error)
 }
 
 // Processor implements the InputProcessor interface.
@@ -26,44 +45,227 @@ func NewProcessor(config config.InputConfig) *Processor {
 	}
 }
 
-// LoadInputs loads and validates input strings from a file.
-func (p *Processor) LoadInputs(filePath string) ([]string, error) {
-	file, err := os.Open(filePath)
+// inputCollector accumulates the input lines of one or more files, along with the diagnostics of the invalid ones.
+type inputCollector struct {
+	lines  []Line
+	diags  []Diagnostic
+	loaded int  // number of lines loaded rather than skipped
+	offset int  // number of lines read from the previous files
+	full   bool // whether MaxLineCount was reached, after which no more lines are read
+}
+
+// LoadInputs loads and validates input strings from a file, which may also be a directory or a glob pattern of plain or compressed files.
+// lines violating a constraint are dropped, or fail the load with a *diagnostics.Error in strict mode.
+func (p *Processor) LoadInputs(ctx context.Context, filePath string) ([]string, error) {
+	lines, err := p.LoadLines(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return Texts(lines), nil
+}
+
+// LoadInputsFrom loads and validates input strings from the given reader.
+func (p *Processor) LoadInputsFrom(ctx context.Context, r io.Reader) ([]string, error) {
+	lines, err := p.LoadLinesFrom(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+	return Texts(lines), nil
+}
+
+// LoadLines loads the input lines of a file along with their line numbers, like LoadInputs, including the non-blank lines skipped for violating a constraint.
+func (p *Processor) LoadLines(ctx context.Context, filePath string) ([]Line, error) {
+	collector, err := p.readInputsFromFile(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return p.checkDiagnostics(collector)
+}
+
+// LoadLinesFrom loads the input lines of the given reader along with their line numbers, including the non-blank lines skipped for violating a constraint.
+func (p *Processor) LoadLinesFrom(ctx context.Context, r io.Reader) ([]Line, error) {
+	collector := &inputCollector{}
+	if err := p.scanAndFilterInputs(ctx, r, "", collector); err != nil {
+		return nil, err
+	}
+	return p.checkDiagnostics(collector)
+}
+
+// Texts returns the texts of the given lines that were loaded rather than skipped, in order.
+func Texts(lines []Line) []string {
+	var texts []string
+	for _, line := range lines {
+		if line.Skipped == "" {
+			texts = append(texts, line.Text)
+		}
+	}
+	return texts
+}
+
+// Validate reports every input line of a file violating a constraint, without failing on them.
+// an error is only returned when the file cannot be read.
+func (p *Processor) Validate(ctx context.Context, filePath string) ([]Diagnostic, error) {
+	collector, err := p.readInputsFromFile(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	return collector.diags, nil
+}
+
+// ValidateFrom reports every input line of the given reader violating a constraint, without failing on them.
+func (p *Processor) ValidateFrom(ctx context.Context, r io.Reader) ([]Diagnostic, error) {
+	collector := &inputCollector{}
+	if err := p.scanAndFilterInputs(ctx, r, "", collector); err != nil {
+		return nil, err
+	}
+	return collector.diags, nil
+}
+
+// utility to fail with the collected diagnostics in strict mode, and to log how many lines were dropped otherwise.
+func (p *Processor) checkDiagnostics(collector *inputCollector) ([]Line, error) {
+	if len(collector.diags) > 0 {
+		if p.config.Strict {
+			return nil, &diagnostics.Error{Diagnostics: collector.diags}
+		}
+		utils.Log.WithField("violationCount", len(collector.diags)).Warn("Dropped input lines violating constraints, run validate to list them")
+	}
+	return collector.lines, nil
+}
+
+// readInputsFromFile reads input lines from the given file path, which may also be a directory or a glob pattern of plain or compressed files.
+// files are read one at a time, so that diagnostics know the file and line they were read from.
+func (p *Processor) readInputsFromFile(ctx context.Context, filePath string) (*inputCollector, error) {
+	files, err := utils.ExpandPaths(filePath)
 	if err != nil {
 		utils.Log.WithError(err).Error("Failed to open input file")
 		return nil, err
 	}
+
+	collector := &inputCollector{}
+	for _, name := range files {
+		if collector.full {
+			break
+		}
+		if err := p.readInputsFromSingleFile(ctx, name, collector); err != nil {
+			return nil, err
+		}
+	}
+	return collector, nil
+}
+
+// utility to read input lines from a single plain or compressed file.
+func (p *Processor) readInputsFromSingleFile(ctx context.Context, name string, collector *inputCollector) error {
+	file, err := utils.OpenFile(name)
+	if err != nil {
+		utils.Log.WithError(err).Error("Failed to open input file")
+		return err
+	}
 	defer file.Close()
 
-	return p.scanAndFilterInputs(file), nil
+	if err := p.scanAndFilterInputs(ctx, file, name, collector); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
 }
 
-// utility to checks if an input line is valid according to the configuration.
-func (p *Processor) isValidInput(input string) bool {
-	length := len(input)
-	isValid := length >= p.config.MinLineLength && length <= p.config.MaxLineLength
+// utility to check an input line against the length constraints of the configuration, returning the violated rule if any.
+// the length of a line is measured in user-perceived characters, not bytes, leaving out punctuation and whitespace when those are ignored.
+func (p *Processor) checkInput(input string) diagnostics.Rule {
+	length := utils.GraphemeCount(input)
+	if p.config.IgnorePunctuation {
+		length = len(utils.WithoutPunctuation(utils.Graphemes(input)))
+	}
+
+	var rule diagnostics.Rule
+	switch {
+	case length < p.config.MinLineLength:
+		rule = diagnostics.RuleLineTooShort
+	case length > p.config.MaxLineLength:
+		rule = diagnostics.RuleLineTooLong
+	}
 
 	utils.Log.WithFields(map[string]interface{}{
 		"input":   input,
-		"isValid": isValid,
+		"isValid": rule == "",
 	}).Debug("Validating input line from input file against configuration")
 
-	return isValid
+	return rule
 }
 
-// scanAndFilterInputs scans and filters input lines from a file.
-func (p *Processor) scanAndFilterInputs(file *os.File) []string {
-	scanner := bufio.NewScanner(file)
-	var inputs []string
-	for scanner.Scan() {
+// utility to apply the long line policy to an input line, returning the lines to keep in its place, without their number, and the rule it violates, if any.
+// a long line is truncated or split when the policy says so, and pieces violating a constraint, such as a remainder shorter than MinLineLength,
+// are returned as skipped lines so that they are reported like any other. pieces left blank once trimmed are dropped silently, like blank lines.
+func (p *Processor) linesOf(input string) ([]Line, diagnostics.Rule) {
+	rule := p.checkInput(input)
+	if rule == "" {
+		return []Line{{Text: input}}, ""
+	}
+	if rule != diagnostics.RuleLineTooLong {
+		return nil, rule
+	}
+
+	policy := LongLinePolicy(p.config.LongLinePolicy)
+	if policy != LongLineTruncate && policy != LongLineSplit {
+		return nil, rule
+	}
+	pieces := splitLine(input, p.config.MaxLineLength, p.config.IgnorePunctuation)
+	if policy == LongLineTruncate {
+		pieces = pieces[:1]
+	}
+
+	var lines []Line
+	for _, piece := range pieces {
+		if piece = strings.TrimSpace(piece); piece != "" {
+			lines = append(lines, Line{Text: piece, Skipped: p.checkInput(piece)})
+		}
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"policy":     policy,
+		"pieceCount": len(lines),
+	}).Debug("Applied long line policy to input line")
+
+	return lines, ""
+}
+
+// scanAndFilterInputs scans and filters input lines from a reader into the given collector, stopping once the given context is done.
+// every violation is reported as a diagnostic and collected as a skipped line, except for blank lines which are skipped silently.
+// once MaxLineCount is reached, the first valid line beyond it is reported and no more lines are read.
+// read errors, including lines longer than MaxTokenSize bytes, are returned rather than ending the input early.
+func (p *Processor) scanAndFilterInputs(ctx context.Context, r io.Reader, file string, collector *inputCollector) error {
+	scanner := utils.NewScanner(r, p.config.MaxTokenSize)
+	line := 1
+	for ; scanner.Scan(); line++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
 		input := strings.TrimSpace(scanner.Text())
-		if p.isValidInput(input) {
-			inputs = append(inputs, input)
-			if len(inputs) >= p.config.MaxLineCount {
+		lines, rule := p.linesOf(input)
+		number := collector.offset + line
+		if rule != "" {
+			if input != "" {
+				collector.diags = append(collector.diags, diagnostics.New(file, line, rule, input))
+				collector.lines = append(collector.lines, Line{Number: number, Text: input, Skipped: rule})
+			}
+			continue
+		}
+		for _, l := range lines {
+			input := l.Text
+			if l.Skipped != "" {
+				collector.diags = append(collector.diags, diagnostics.New(file, line, l.Skipped, input))
+				collector.lines = append(collector.lines, Line{Number: number, Text: input, Skipped: l.Skipped})
+				continue
+			}
+			if collector.loaded >= p.config.MaxLineCount {
 				utils.Log.WithField("maxLineCount", p.config.MaxLineCount).Warn("Reached max line count, will not process any more lines from input file")
-				break
+				collector.diags = append(collector.diags, diagnostics.New(file, line, diagnostics.RuleTooManyLines, input))
+				collector.full = true
+				return nil
 			}
+			collector.lines = append(collector.lines, Line{Number: number, Text: input})
+			collector.loaded++
 		}
 	}
-	return inputs
+	collector.offset += line - 1
+	return utils.ScanError(scanner, line, p.config.MaxTokenSize)
 }
@@ -1,9 +1,16 @@
 package input
 
 import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"strings"
 	"testing"
+	"testing/iotest"
 
 	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -15,7 +22,7 @@ func TestLoadInputs_Success(t *testing.T) {
 		MaxLineCount:  100,
 	})
 
-	lines, err := processor.LoadInputs("../../test_data/input.txt")
+	lines, err := processor.LoadInputs(context.Background(), "../../test_data/input.txt")
 	assert.NoError(t, err)
 	assert.NotEmpty(t, lines, "Input file should not be empty")
 }
@@ -28,7 +35,7 @@ func TestLoadInputs_LineLengthConstraints(t *testing.T) {
 		MaxLineCount:  100,
 	})
 
-	lines, err := processor.LoadInputs("../../test_data/input.txt")
+	lines, err := processor.LoadInputs(context.Background(), "../../test_data/input.txt")
 	assert.NoError(t, err)
 
 	for _, line := range lines {
@@ -46,7 +53,191 @@ func TestLoadInputs_MaxLineCount(t *testing.T) {
 		MaxLineCount:  maxLines,
 	})
 
-	lines, err := processor.LoadInputs("../../test_data/input_invalid.txt")
+	lines, err := processor.LoadInputs(context.Background(), "../../test_data/input_invalid.txt")
 	assert.NoError(t, err)
 	assert.Len(t, lines, maxLines, "The number of loaded lines should not exceed the maximum count")
 }
+
+// TestLoadInputs_CharacterLength checks that line lengths are measured in characters rather than bytes.
+func TestLoadInputs_CharacterLength(t *testing.T) {
+	processor := NewProcessor(config.InputConfig{
+		MinLineLength: 2,
+		MaxLineLength: 4,
+		MaxLineCount:  10,
+	})
+
+	lines, err := processor.LoadInputsFrom(context.Background(), strings.NewReader("слово\nмир\n👍🏽👍🏽\nab\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"мир", "👍🏽👍🏽", "ab"}, lines)
+}
+
+// TestLoadInputs_IgnorePunctuationLength checks that punctuation and whitespace do not count towards line length once ignored.
+func TestLoadInputs_IgnorePunctuationLength(t *testing.T) {
+	processor := NewProcessor(config.InputConfig{
+		MinLineLength:     2,
+		MaxLineLength:     4,
+		MaxLineCount:      10,
+		IgnorePunctuation: true,
+	})
+
+	lines, err := processor.LoadInputsFrom(context.Background(), strings.NewReader("a, b, c, d!\na b c d e\n-!-\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a, b, c, d!"}, lines)
+}
+
+// TestLoadInputs_Directory checks that every file of a directory is read, in lexical order.
+func TestLoadInputs_Directory(t *testing.T) {
+	processor := NewProcessor(config.InputConfig{
+		MinLineLength: 1,
+		MaxLineLength: 500,
+		MaxLineCount:  100,
+	})
+
+	lines, err := processor.LoadInputs(context.Background(), "../../test_data/compressed")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"axpaj", "dnrbt"}, lines)
+}
+
+// TestValidate_ReportsViolations checks that every violation is reported with its line, rule and value, up to the first line beyond MaxLineCount.
+func TestValidate_ReportsViolations(t *testing.T) {
+	processor := NewProcessor(config.InputConfig{
+		MinLineLength: 2,
+		MaxLineLength: 5,
+		MaxLineCount:  2,
+	})
+
+	diags, err := processor.ValidateFrom(context.Background(), strings.NewReader("ab\nx\n\nabcdefg\ncd\nef\ngh\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{
+		{Line: 2, Rule: diagnostics.RuleLineTooShort, Value: "x"},
+		{Line: 4, Rule: diagnostics.RuleLineTooLong, Value: "abcdefg"},
+		{Line: 6, Rule: diagnostics.RuleTooManyLines, Value: "ef"},
+	}, diags)
+}
+
+// TestLoadInputs_Strict checks that strict mode fails on violations, naming the file they were read from.
+func TestLoadInputs_Strict(t *testing.T) {
+	processor := NewProcessor(config.InputConfig{
+		MinLineLength: 2,
+		MaxLineLength: 5,
+		MaxLineCount:  100,
+		Strict:        true,
+	})
+
+	_, err := processor.LoadInputs(context.Background(), "../../test_data/input_invalid.txt")
+	var diagErr *diagnostics.Error
+	assert.ErrorAs(t, err, &diagErr)
+	assert.NotEmpty(t, diagErr.Diagnostics)
+	for _, d := range diagErr.Diagnostics {
+		assert.Equal(t, "../../test_data/input_invalid.txt", d.File)
+	}
+}
+
+// TestLoadInputs_TokenTooLong checks that a line over MaxTokenSize fails the load rather than ending the input early.
+func TestLoadInputs_TokenTooLong(t *testing.T) {
+	input := "ab\n" + strings.Repeat("x", 200) + "\ncd\n"
+	processor := NewProcessor(config.InputConfig{
+		MinLineLength: 2,
+		MaxLineLength: 500,
+		MaxLineCount:  100,
+		MaxTokenSize:  100,
+	})
+
+	_, err := processor.LoadInputsFrom(context.Background(), strings.NewReader(input))
+	assert.ErrorIs(t, err, bufio.ErrTooLong)
+	assert.Contains(t, err.Error(), "line 2")
+
+	processor = NewProcessor(config.InputConfig{
+		MinLineLength: 2,
+		MaxLineLength: 500,
+		MaxLineCount:  100,
+		MaxTokenSize:  1024,
+	})
+	lines, err := processor.LoadInputsFrom(context.Background(), strings.NewReader(input))
+	assert.NoError(t, err)
+	assert.Len(t, lines, 3)
+}
+
+// TestLoadInputs_ReadError checks that read errors are returned.
+func TestLoadInputs_ReadError(t *testing.T) {
+	processor := NewProcessor(config.InputConfig{MinLineLength: 2, MaxLineLength: 500, MaxLineCount: 100})
+	failure := errors.New("disk on fire")
+
+	_, err := processor.LoadInputsFrom(context.Background(), io.MultiReader(strings.NewReader("ab\n"), iotest.ErrReader(failure)))
+	assert.ErrorIs(t, err, failure)
+}
+
+// TestLoadInputs_LongLinePolicy checks that long lines are rejected, truncated or split according to the policy.
+func TestLoadInputs_LongLinePolicy(t *testing.T) {
+	load := func(policy LongLinePolicy) []string {
+		processor := NewProcessor(config.InputConfig{
+			MinLineLength:  2,
+			MaxLineLength:  4,
+			MaxLineCount:   100,
+			LongLinePolicy: string(policy),
+		})
+		lines, err := processor.LoadInputsFrom(context.Background(), strings.NewReader("ab\nabcdéfghi\ncd\n"))
+		assert.NoError(t, err)
+		return lines
+	}
+
+	assert.Equal(t, []string{"ab", "cd"}, load(LongLineReject))
+	assert.Equal(t, []string{"ab", "abcd", "cd"}, load(LongLineTruncate))
+	assert.Equal(t, []string{"ab", "abcd", "éfgh", "cd"}, load(LongLineSplit), "A remainder shorter than MinLineLength should not be loaded")
+}
+
+// TestLoadLines_ReportsShortPieces checks that a piece of a long line shorter than MinLineLength is reported as a skipped line,
+// and fails the load in strict mode.
+func TestLoadLines_ReportsShortPieces(t *testing.T) {
+	cfg := config.InputConfig{
+		MinLineLength:  2,
+		MaxLineLength:  4,
+		MaxLineCount:   100,
+		LongLinePolicy: string(LongLineSplit),
+	}
+
+	lines, err := NewProcessor(cfg).LoadLinesFrom(context.Background(), strings.NewReader("ab\nabcdefghi\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Line{
+		{Number: 1, Text: "ab"},
+		{Number: 2, Text: "abcd"},
+		{Number: 2, Text: "efgh"},
+		{Number: 2, Text: "i", Skipped: diagnostics.RuleLineTooShort},
+	}, lines)
+
+	diags, err := NewProcessor(cfg).ValidateFrom(context.Background(), strings.NewReader("ab\nabcdefghi\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Diagnostic{{Line: 2, Rule: diagnostics.RuleLineTooShort, Value: "i"}}, diags)
+
+	cfg.Strict = true
+	_, err = NewProcessor(cfg).LoadLinesFrom(context.Background(), strings.NewReader("ab\nabcdefghi\n"))
+	var diagErr *diagnostics.Error
+	assert.ErrorAs(t, err, &diagErr)
+}
+
+// TestSplitLine_IgnorePunctuation checks that ignored punctuation does not count towards the length of a piece.
+func TestSplitLine_IgnorePunctuation(t *testing.T) {
+	assert.Equal(t, []string{"a-b, ", "cd"}, splitLine("a-b, cd", 2, true))
+	assert.Equal(t, []string{"a-", "b,", " c", "d"}, splitLine("a-b, cd", 2, false))
+}
+
+// TestLoadLines_Numbers checks that lines keep their line numbers, and that skipped lines are kept along with the rule they violate.
+func TestLoadLines_Numbers(t *testing.T) {
+	processor := NewProcessor(config.InputConfig{
+		MinLineLength:  2,
+		MaxLineLength:  4,
+		MaxLineCount:   100,
+		LongLinePolicy: string(LongLineSplit),
+	})
+
+	lines, err := processor.LoadLinesFrom(context.Background(), strings.NewReader("ab\nx\n\nabcdefgh\ncd\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, []Line{
+		{Number: 1, Text: "ab"},
+		{Number: 2, Text: "x", Skipped: diagnostics.RuleLineTooShort},
+		{Number: 4, Text: "abcd"},
+		{Number: 4, Text: "efgh"},
+		{Number: 5, Text: "cd"},
+	}, lines)
+	assert.Equal(t, []string{"ab", "abcd", "efgh", "cd"}, Texts(lines))
+}
@@ -0,0 +1,78 @@
+package input
+
+import (
+	"context"
+	"io"
+	"strings"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// interface for streaming validated input strings one at a time.
+type StreamingInputProcessor interface {
+	StreamInputs(ctx context.Context, r io.Reader, emit func(input string) error) error
+	StreamLines(ctx context.Context, r io.Reader, emit func(line Line) error) error
+}
+
+// StreamProcessor implements the StreamingInputProcessor interface.
+// it validates lines like Processor, but holds a single line at a time and never stops at MaxLineCount.
+type StreamProcessor struct {
+	Processor
+}
+
+// creates a new input StreamProcessor with the given configuration.
+func NewStreamProcessor(config config.InputConfig) *StreamProcessor {
+	return &StreamProcessor{
+		Processor: Processor{config: config},
+	}
+}
+
+// StreamInputs reads input lines from the given reader and calls emit for every valid one, in order.
+// it stops at the end of the reader, at the first read error, as soon as emit returns an error, or once the given context is done.
+// in strict mode it also stops at the first line violating a constraint, with a *diagnostics.Error reporting its line number within the stream.
+// long lines are truncated or split according to the long line policy, like Processor does.
+func (p *StreamProcessor) StreamInputs(ctx context.Context, r io.Reader, emit func(input string) error) error {
+	return p.StreamLines(ctx, r, func(line Line) error {
+		if line.Skipped != "" {
+			return nil
+		}
+		return emit(line.Text)
+	})
+}
+
+// StreamLines reads input lines from the given reader and calls emit for every one along with its line number, in order, like StreamInputs.
+// non-blank lines violating a constraint are emitted too, marked as skipped, unless in strict mode.
+func (p *StreamProcessor) StreamLines(ctx context.Context, r io.Reader, emit func(line Line) error) error {
+	scanner := utils.NewScanner(r, p.config.MaxTokenSize)
+	emitted := 0
+	number := 1
+	for ; scanner.Scan(); number++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		input := strings.TrimSpace(scanner.Text())
+		lines, rule := p.linesOf(input)
+		if rule != "" {
+			if input == "" {
+				continue
+			}
+			lines = []Line{{Text: input, Skipped: rule}}
+		}
+		for _, line := range lines {
+			if line.Skipped != "" && p.config.Strict {
+				return &diagnostics.Error{Diagnostics: []diagnostics.Diagnostic{diagnostics.New("", number, line.Skipped, line.Text)}}
+			}
+			line.Number = number
+			if err := emit(line); err != nil {
+				return err
+			}
+			emitted++
+		}
+	}
+
+	utils.Log.WithField("lineCount", emitted).Debug("Streamed input lines")
+
+	return utils.ScanError(scanner, number, p.config.MaxTokenSize)
+}
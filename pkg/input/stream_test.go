@@ -0,0 +1,89 @@
+package input
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestStreamInputs_IgnoresMaxLineCount checks that every valid line is streamed, in order.
+func TestStreamInputs_IgnoresMaxLineCount(t *testing.T) {
+	processor := NewStreamProcessor(config.InputConfig{
+		MinLineLength: 2,
+		MaxLineLength: 5,
+		MaxLineCount:  1,
+	})
+
+	var lines []string
+	err := processor.StreamInputs(context.Background(), strings.NewReader("ab\nx\nabcdefg\ncd\nef\n"), func(input string) error {
+		lines = append(lines, input)
+		return nil
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ab", "cd", "ef"}, lines, "Invalid lines should be skipped and MaxLineCount ignored")
+}
+
+// TestStreamInputs_StopsOnEmitError checks that an error returned by emit ends the stream.
+func TestStreamInputs_StopsOnEmitError(t *testing.T) {
+	processor := NewStreamProcessor(config.InputConfig{MinLineLength: 1, MaxLineLength: 5})
+	stop := errors.New("stop")
+
+	calls := 0
+	err := processor.StreamInputs(context.Background(), strings.NewReader("a\nb\nc\n"), func(input string) error {
+		calls++
+		return stop
+	})
+
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+// TestStreamLines_ReportsShortPieces checks that a piece of a long line shorter than MinLineLength is streamed as a skipped line,
+// and stops the stream in strict mode.
+func TestStreamLines_ReportsShortPieces(t *testing.T) {
+	cfg := config.InputConfig{MinLineLength: 2, MaxLineLength: 4, LongLinePolicy: string(LongLineTruncate)}
+	stream := func(cfg config.InputConfig) ([]Line, error) {
+		var lines []Line
+		err := NewStreamProcessor(cfg).StreamLines(context.Background(), strings.NewReader("ab\nx       abcdef\n"), func(line Line) error {
+			lines = append(lines, line)
+			return nil
+		})
+		return lines, err
+	}
+
+	lines, err := stream(cfg)
+	assert.NoError(t, err)
+	assert.Equal(t, []Line{
+		{Number: 1, Text: "ab"},
+		{Number: 2, Text: "x", Skipped: diagnostics.RuleLineTooShort},
+	}, lines)
+
+	cfg.Strict = true
+	lines, err = stream(cfg)
+	var diagErr *diagnostics.Error
+	assert.ErrorAs(t, err, &diagErr)
+	assert.Equal(t, []Diagnostic{{Line: 2, Rule: diagnostics.RuleLineTooShort, Value: "x"}}, diagErr.Diagnostics)
+	assert.Equal(t, []Line{{Number: 1, Text: "ab"}}, lines)
+}
+
+// TestStreamInputs_Strict checks that strict mode stops the stream at the first violation.
+func TestStreamInputs_Strict(t *testing.T) {
+	processor := NewStreamProcessor(config.InputConfig{MinLineLength: 2, MaxLineLength: 5, Strict: true})
+
+	var lines []string
+	err := processor.StreamInputs(context.Background(), strings.NewReader("ab\n\nx\ncd\n"), func(input string) error {
+		lines = append(lines, input)
+		return nil
+	})
+
+	var diagErr *diagnostics.Error
+	assert.ErrorAs(t, err, &diagErr)
+	assert.Equal(t, []Diagnostic{{Line: 3, Rule: diagnostics.RuleLineTooShort, Value: "x"}}, diagErr.Diagnostics)
+	assert.Equal(t, []string{"ab"}, lines)
+}
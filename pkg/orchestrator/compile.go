@@ -0,0 +1,43 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+
+	"github.com/1x-eng/cipherlex/pkg/index"
+	"github.com/1x-eng/cipherlex/pkg/wordmatcher"
+)
+
+// ErrCompileReader is returned when compiling a dictionary given as a reader, which cannot be fingerprinted to detect stale indexes.
+var ErrCompileReader = errors.New("only dictionaries given as paths can be compiled")
+
+// Compile loads, validates and compiles the dictionaries of the given options into an index, ready to be written with WriteFile
+// and used by later runs through Options.IndexPath. the index depends on the mode, text and dictionary settings of the configuration.
+// an index is all or nothing: once the given context is done while the dictionaries are loaded, no index is returned, only a *LoadError wrapping the context's error.
+func Compile(ctx context.Context, opts Options) (*index.Index, error) {
+	if err := validateOptions(opts); err != nil {
+		return nil, err
+	}
+
+	sources := opts.Dictionaries
+	if len(sources) == 0 {
+		sources = []DictionarySource{{Path: opts.DictionaryPath, Reader: opts.Dictionary}}
+	}
+	var dictionaries []index.Dictionary
+	for _, source := range sources {
+		if source.Reader != nil {
+			return nil, &LoadError{Source: "dictionary", Path: source.Path, Err: ErrCompileReader}
+		}
+		dictionaries = append(dictionaries, index.Dictionary{Name: source.Name, Path: source.Path})
+	}
+
+	dictEntries, err := loadAndProcessDictionary(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	ix, err := index.New(index.SettingsOf(opts.Config), dictionaries, wordmatcher.CompileEntries(dictEntries, opts.Config))
+	if err != nil {
+		return nil, &LoadError{Source: "dictionary", Err: err}
+	}
+	return ix, nil
+}
@@ -0,0 +1,44 @@
+package orchestrator
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/1x-eng/cipherlex/pkg/index"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestRun_Index checks that a run using a compiled index has the same results as a run reading its dictionaries.
+func TestRun_Index(t *testing.T) {
+	dir := t.TempDir()
+	animals := filepath.Join(dir, "animals.txt")
+	plants := filepath.Join(dir, "plants.txt")
+	assert.NoError(t, os.WriteFile(animals, []byte("axpaj\ndnrbt\tignore-case\n"), 0o644))
+	assert.NoError(t, os.WriteFile(plants, []byte("pjxdn\texact\naxpaj\n"), 0o644))
+	indexPath := filepath.Join(dir, "dict.idx")
+
+	dictionaries := []DictionarySource{{Name: "animals", Path: animals}, {Name: "plants", Path: plants}}
+	ix, err := Compile(context.Background(), Options{Dictionaries: dictionaries, Config: testConfig()})
+	assert.NoError(t, err)
+	assert.NoError(t, ix.WriteFile(indexPath))
+
+	input := "aapxjDNRBTvldptfzbbdbbzxtndrvjblnzjfpvhdhhpxjdnrbt\npjxdn xndjp\n"
+	expected, err := Run(context.Background(), Options{Dictionaries: dictionaries, Input: strings.NewReader(input), Config: testConfig()})
+	assert.NoError(t, err)
+	report, err := Run(context.Background(), Options{IndexPath: indexPath, Input: strings.NewReader(input), Config: testConfig()})
+	assert.NoError(t, err)
+	assert.Equal(t, expected, report)
+
+	cfg := testConfig()
+	cfg.Mode = "exact"
+	_, err = Run(context.Background(), Options{IndexPath: indexPath, Input: strings.NewReader(input), Config: cfg})
+	assert.ErrorIs(t, err, index.ErrStale)
+}
+
+func TestCompile_RequiresPaths(t *testing.T) {
+	_, err := Compile(context.Background(), Options{Dictionary: strings.NewReader("axpaj\n"), Config: testConfig()})
+	assert.ErrorIs(t, err, ErrCompileReader)
+}
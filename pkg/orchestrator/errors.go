@@ -0,0 +1,45 @@
+package orchestrator
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrMissingDictionary is returned when Options specify neither a dictionary reader nor a dictionary path.
+	ErrMissingDictionary = errors.New("no dictionary given")
+	// ErrMissingInput is returned when Options specify neither an input reader nor an input path.
+	ErrMissingInput = errors.New("no input given")
+)
+
+// ConfigError is returned when the configuration holds a value cipherlex does not understand.
+type ConfigError struct {
+	Field string
+	Err   error
+}
+
+func (e *ConfigError) Error() string {
+	return fmt.Sprintf("invalid %s: %v", e.Field, e.Err)
+}
+
+func (e *ConfigError) Unwrap() error {
+	return e.Err
+}
+
+// LoadError is returned when the dictionary, the input or the index cannot be loaded.
+type LoadError struct {
+	Source string // "dictionary", "input" or "index"
+	Path   string // empty when loading from a reader
+	Err    error
+}
+
+func (e *LoadError) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("failed to load %s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("failed to load %s %q: %v", e.Source, e.Path, e.Err)
+}
+
+func (e *LoadError) Unwrap() error {
+	return e.Err
+}
@@ -1,43 +1,288 @@
 package orchestrator
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"io"
+	"runtime"
+	"sync"
 
 	"github.com/1x-eng/cipherlex/pkg/config"
 	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/index"
 	"github.com/1x-eng/cipherlex/pkg/input"
+	"github.com/1x-eng/cipherlex/pkg/output"
 	"github.com/1x-eng/cipherlex/pkg/utils"
 	"github.com/1x-eng/cipherlex/pkg/wordmatcher"
 )
 
-// Processor is the main entrypoint for the application, it loads and processes the dictionary and input files and then finds matches.
-func Processor(dictPath, inputPath string, cfg config.AppConfig) {
-	dictWords := loadAndProcessDictionary(dictPath, cfg.DictionaryConfig)
-	inputLines := loadAndProcessInput(inputPath, cfg.InputConfig)
+// Options describes where a run reads its dictionary and input from, and how it is configured.
+// readers take precedence over paths, paths are only used for the readers left nil.
+// Dictionaries, when given, replaces DictionaryPath and Dictionary with several named dictionaries, whose results are broken down by dictionary.
+// IndexPath, when given, replaces all of them with an index compiled by Compile, which must match the configuration and its dictionary files.
+type Options struct {
+	DictionaryPath string
+	InputPath      string
+	IndexPath      string
+	Dictionary     io.Reader
+	Input          io.Reader
+	Dictionaries   []DictionarySource
+	Config         config.AppConfig
+}
+
+// DictionarySource is one of several named dictionaries, read from its reader if given and from its path otherwise.
+type DictionarySource struct {
+	Name   string
+	Path   string
+	Reader io.Reader
+}
+
+// Report holds the run, a result for every processed input line and how the run ended.
+type Report struct {
+	Run     output.RunInfo
+	Results []output.LineResult
+	Summary output.Summary
+}
+
+// Run is the main entrypoint for the application, it loads and processes the dictionary and input, and then finds matches in every input line.
+// once the given context is done, Run returns the results of the lines completed so far along with the context's error,
+// and the report's summary tells whether the run timed out or was canceled.
+func Run(ctx context.Context, opts Options) (Report, error) {
+	if err := validateOptions(opts); err != nil {
+		return Report{}, err
+	}
+
+	dictWords, err := loadDictionaryWords(ctx, &opts)
+	if err != nil {
+		return Report{}, err
+	}
+	inputLines, err := loadAndProcessInput(ctx, opts)
+	if err != nil {
+		return Report{}, err
+	}
 
-	chunkSize := determineChunkSize(dictWords, inputLines, cfg.InputConfig)
-	processMatches(inputLines, dictWords, chunkSize, cfg)
+	chunkSize := determineChunkSize(dictionary.Words(wordmatcher.Entries(dictWords)), input.Texts(inputLines), opts.Config.InputConfig)
+	report := Report{Run: runInfo(opts, chunkSize)}
+	report.Results, err = processMatches(ctx, inputLines, dictWords, dictionaryNames(opts), chunkSize, opts.Config)
+	report.Summary = output.Summary{Status: statusOf(err), Lines: len(report.Results)}
+	return report, err
 }
 
-// loads and processes the dictionary file.
-func loadAndProcessDictionary(dictPath string, dictConfig config.DictionaryConfig) []string {
-	dictProcessor := dictionary.NewProcessor(dictConfig)
-	dictWords, err := dictProcessor.LoadDictionary(dictPath)
+// Write writes the given report using the given formatter.
+func Write(formatter output.Formatter, report Report) error {
+	if err := formatter.Start(report.Run); err != nil {
+		return err
+	}
+	for _, result := range report.Results {
+		if err := formatter.WriteResult(result); err != nil {
+			return err
+		}
+	}
+	return formatter.Finish(report.Summary)
+}
+
+// utility to derive the status of a run from the error it ended with.
+func statusOf(err error) output.Status {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return output.StatusTimedOut
+	case errors.Is(err, context.Canceled):
+		return output.StatusCanceled
+	case err != nil:
+		return output.StatusFailed
+	default:
+		return output.StatusCompleted
+	}
+}
+
+// checks the configuration and the dictionaries of the given options.
+func validateOptions(opts Options) error {
+	if err := validateConfig(opts.Config); err != nil {
+		return err
+	}
+	return validateDictionaries(opts.Dictionaries)
+}
+
+// checks that every one of several dictionaries has a distinct name.
+func validateDictionaries(sources []DictionarySource) error {
+	names := make(map[string]struct{})
+	for _, source := range sources {
+		if source.Name == "" {
+			return &ConfigError{Field: "dictionaries", Err: fmt.Errorf("dictionary %q has no name", source.Path)}
+		}
+		if _, exists := names[source.Name]; exists {
+			return &ConfigError{Field: "dictionaries", Err: fmt.Errorf("dictionary name %q is used more than once", source.Name)}
+		}
+		names[source.Name] = struct{}{}
+	}
+	return nil
+}
+
+// checks that the matching mode, engines and trie implementation of the given configuration are known, and that they can be used together,
+// that the gapped engine has a positive max span no shorter than the longest word, that the exact engine finds exact occurrences, and that the normalization, long line policy and numbering are known.
+func validateConfig(cfg config.AppConfig) error {
+	mode, err := wordmatcher.ParseMode(cfg.Mode)
+	if err != nil {
+		return &ConfigError{Field: "mode", Err: err}
+	}
+	engine, err := wordmatcher.ParseEngine(cfg.Engine)
+	if err != nil {
+		return &ConfigError{Field: "engine", Err: err}
+	}
+	if err := wordmatcher.CheckEngine(engine, mode); err != nil {
+		return &ConfigError{Field: "engine", Err: err}
+	}
+	metric, err := wordmatcher.ParseMetric(cfg.DistanceMetric, mode)
+	if err != nil {
+		return &ConfigError{Field: "distance_metric", Err: err}
+	}
+	if engine == wordmatcher.EngineFuzzy {
+		if err := wordmatcher.CheckMetric(metric, mode); err != nil {
+			return &ConfigError{Field: "distance_metric", Err: err}
+		}
+	}
+	impl, err := utils.ParseTrieImpl(cfg.TrieImpl)
+	if err != nil {
+		return &ConfigError{Field: "trie_impl", Err: err}
+	}
+	if err := wordmatcher.CheckTrieImpl(impl, engine); err != nil {
+		return &ConfigError{Field: "trie_impl", Err: err}
+	}
+	if engine == wordmatcher.EngineGapped && cfg.MaxSpan < 1 {
+		return &ConfigError{Field: "max_span", Err: fmt.Errorf("max span must be at least 1 character, got %d", cfg.MaxSpan)}
+	}
+	if engine == wordmatcher.EngineGapped && cfg.MaxSpan < cfg.MaxWordLength {
+		return &ConfigError{Field: "max_span", Err: fmt.Errorf("max span of %d characters is shorter than the maximum word length of %d, longer words could never be found", cfg.MaxSpan, cfg.MaxWordLength)}
+	}
+	if cfg.ExactEngine != "" {
+		exactEngine, err := wordmatcher.ParseEngine(cfg.ExactEngine)
+		if err != nil {
+			return &ConfigError{Field: "exact_engine", Err: err}
+		}
+		if err := wordmatcher.CheckExactEngine(exactEngine); err != nil {
+			return &ConfigError{Field: "exact_engine", Err: err}
+		}
+	}
+	if _, err := utils.ParseNormalization(cfg.Normalization); err != nil {
+		return &ConfigError{Field: "normalization", Err: err}
+	}
+	if _, err := input.ParseLongLinePolicy(cfg.LongLinePolicy); err != nil {
+		return &ConfigError{Field: "long_line_policy", Err: err}
+	}
+	if _, err := output.ParseNumbering(cfg.Numbering); err != nil {
+		return &ConfigError{Field: "numbering", Err: err}
+	}
+	return nil
+}
+
+// loads the compiled words of the dictionaries of the given options, from their index if given and from the dictionaries themselves otherwise.
+// an index replaces the dictionaries of the options with the dictionaries it was compiled from, so that the run is described and broken down by them.
+func loadDictionaryWords(ctx context.Context, opts *Options) ([]wordmatcher.CompiledWord, error) {
+	if opts.IndexPath == "" {
+		dictEntries, err := loadAndProcessDictionary(ctx, *opts)
+		if err != nil {
+			return nil, err
+		}
+		return wordmatcher.CompileEntries(dictEntries, opts.Config), nil
+	}
+
+	ix, err := index.Load(opts.IndexPath)
+	if err == nil {
+		err = ix.Check(opts.Config)
+	}
 	if err != nil {
-		log.Fatalf("Failed to load dictionary: %v", err)
+		return nil, &LoadError{Source: "index", Path: opts.IndexPath, Err: err}
+	}
+	opts.DictionaryPath, opts.Dictionary, opts.Dictionaries = "", nil, nil
+	if len(ix.Dictionaries) == 1 && ix.Dictionaries[0].Name == "" {
+		opts.DictionaryPath = ix.Dictionaries[0].Path
+	} else {
+		for _, dict := range ix.Dictionaries {
+			opts.Dictionaries = append(opts.Dictionaries, DictionarySource{Name: dict.Name, Path: dict.Path})
+		}
+	}
+	return ix.Words, nil
+}
+
+// loads and processes the dictionaries of the given options, each from its reader if given and from its path otherwise.
+// the entries carry the match policies of annotated dictionaries, and the name of their dictionary when several are used.
+// constraints, such as the maximum dictionary size, apply to every dictionary on its own.
+func loadAndProcessDictionary(ctx context.Context, opts Options) ([]dictionary.Entry, error) {
+	sources := opts.Dictionaries
+	if len(sources) == 0 {
+		sources = []DictionarySource{{Path: opts.DictionaryPath, Reader: opts.Dictionary}}
 	}
-	return dictWords
+
+	dictProcessor := dictionary.NewProcessor(opts.Config.DictionaryConfig)
+	var dictEntries []dictionary.Entry
+	for _, source := range sources {
+		var entries []dictionary.Entry
+		var err error
+		switch {
+		case source.Reader != nil:
+			entries, err = dictProcessor.LoadEntriesFrom(ctx, source.Reader)
+		case source.Path != "":
+			entries, err = dictProcessor.LoadEntries(ctx, source.Path)
+		default:
+			err = ErrMissingDictionary
+		}
+		if err != nil {
+			return nil, &LoadError{Source: "dictionary", Path: source.Path, Err: err}
+		}
+		dictEntries = append(dictEntries, dictionary.Tag(entries, source.Name)...)
+	}
+	return dictEntries, nil
+}
+
+// utility to list the names of the dictionaries of the given options, nil unless several named dictionaries are used.
+func dictionaryNames(opts Options) []string {
+	var names []string
+	for _, source := range opts.Dictionaries {
+		names = append(names, source.Name)
+	}
+	return names
 }
 
-// loads and processes the input file.
-func loadAndProcessInput(inputPath string, inputConfig config.InputConfig) []string {
-	inputProcessor := input.NewProcessor(inputConfig)
-	inputLines, err := inputProcessor.LoadInputs(inputPath)
+// utility to describe the run of the given options.
+func runInfo(opts Options, chunkSize int) output.RunInfo {
+	run := output.RunInfo{
+		DictionaryPath: opts.DictionaryPath,
+		InputPath:      opts.InputPath,
+		ChunkSize:      chunkSize,
+		Config:         opts.Config,
+	}
+	for _, source := range opts.Dictionaries {
+		run.Dictionaries = append(run.Dictionaries, output.DictionaryInfo{Name: source.Name, Path: source.Path})
+	}
+	return run
+}
+
+// loads and processes the input, from its reader if given, from standard input for StdinPath and from its path otherwise.
+// the lines carry their line numbers, and lines skipped for violating a constraint are kept so that they can be reported.
+func loadAndProcessInput(ctx context.Context, opts Options) ([]input.Line, error) {
+	inputProcessor := input.NewProcessor(opts.Config.InputConfig)
+
+	var inputLines []input.Line
+	var err error
+	switch {
+	case opts.Input != nil:
+		inputLines, err = inputProcessor.LoadLinesFrom(ctx, opts.Input)
+	case opts.InputPath == StdinPath:
+		var stdin io.ReadCloser
+		if stdin, err = openStdin(); err == nil {
+			defer stdin.Close()
+			inputLines, err = inputProcessor.LoadLinesFrom(ctx, stdin)
+		}
+	case opts.InputPath != "":
+		inputLines, err = inputProcessor.LoadLines(ctx, opts.InputPath)
+	default:
+		err = ErrMissingInput
+	}
 	if err != nil {
-		log.Fatalf("Failed to load input file: %v", err)
+		return nil, &LoadError{Source: "input", Path: opts.InputPath, Err: err}
 	}
-	return inputLines
+	return inputLines, nil
 }
 
 // dynamically determines the chunk size to use for processing the input file.
@@ -47,12 +292,123 @@ func determineChunkSize(dictWords, inputLines []string, inputConfig config.Input
 	return utils.NewChunkSizeCalculator(inputConfig).DetermineChunkSize(longestWordLength, averageLineLength)
 }
 
-// processes the input lines and finds matches.
-func processMatches(inputLines, dictWords []string, chunkSize int, cfg config.AppConfig) {
-	matcher := wordmatcher.NewMatcher(dictWords, cfg, chunkSize)
+// processes the input lines concurrently and finds matches, returning a result per line in input order, numbered according to the configuration.
+// skipped lines get a result of their own, which only uses up a case number when numbering by line.
+// processing stops early once the given context is done, returning the results of the lines completed so far
+// up to the first line that was not completed, so that case numbering stays stable.
+func processMatches(ctx context.Context, inputLines []input.Line, dictWords []wordmatcher.CompiledWord, dictNames []string, chunkSize int, cfg config.AppConfig) ([]output.LineResult, error) {
+	matcher := wordmatcher.NewMatcherFromCompiled(dictWords, cfg, chunkSize)
+	defer matcher.Close()
+
+	numberer := newCaseNumberer(cfg.Numbering)
+	jobs := make([]lineJob, len(inputLines))
 	for i, line := range inputLines {
-		matches := matcher.FindMatches(line)
-		uniqueCount := matcher.CountUniqueMatches(matches)
-		fmt.Printf("Case #%d: %d\n", i+1, uniqueCount)
+		jobs[i] = numberer.job(line)
+	}
+
+	results := make([]output.LineResult, len(jobs))
+	completed := make([]bool, len(jobs))
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < lineWorkers(cfg); worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				result, err := matchLine(ctx, matcher, dictNames, jobs[i])
+				if err != nil {
+					continue
+				}
+				results[i] = result
+				completed[i] = true
+			}
+		}()
+	}
+
+feed:
+	for i := range jobs {
+		select {
+		case indexes <- i:
+		case <-ctx.Done():
+			break feed
+		}
+	}
+	close(indexes)
+	wg.Wait()
+
+	completedLines := 0
+	for completedLines < len(completed) && completed[completedLines] {
+		completedLines++
+	}
+	if completedLines < len(jobs) {
+		return results[:completedLines], ctx.Err()
+	}
+	return results, nil
+}
+
+// finds the matches of a single input line and summarises them into a line result, broken down by the given dictionaries.
+// a skipped line is not matched, its result only tells why it was skipped.
+func matchLine(ctx context.Context, matcher wordmatcher.LineMatcher, dictNames []string, job lineJob) (output.LineResult, error) {
+	if job.line.Skipped != "" {
+		return output.LineResult{
+			Case:    job.caseNumber,
+			Line:    job.line.Number,
+			Words:   []string{},
+			Skipped: true,
+			Reason:  string(job.line.Skipped),
+		}, nil
+	}
+
+	matches, err := matcher.MatchLine(ctx, job.line.Number, job.line.Text)
+	if err != nil {
+		return output.LineResult{}, err
+	}
+	words := wordmatcher.UniqueWords(matches)
+	result := output.LineResult{
+		Case:  job.caseNumber,
+		Line:  job.line.Number,
+		Count: len(words),
+		Words: words,
+	}
+	for _, name := range dictNames {
+		dictWords := wordmatcher.UniqueWords(wordmatcher.MatchesOf(matches, name))
+		result.ByDictionary = append(result.ByDictionary, output.DictionaryResult{
+			Name:  name,
+			Count: len(dictWords),
+			Words: dictWords,
+		})
+	}
+	return result, nil
+}
+
+// caseNumberer turns input lines into line jobs as they are read, numbering them according to the configured numbering.
+type caseNumberer struct {
+	byLine bool
+	cases  int
+}
+
+// creates a new caseNumberer for the given numbering, unknown numberings number cases.
+func newCaseNumberer(numbering string) *caseNumberer {
+	return &caseNumberer{byLine: output.Numbering(numbering) == output.NumberingLine}
+}
+
+// returns the job of the given line, a skipped line gets case number 0 when cases are numbered, so that it uses up none.
+func (n *caseNumberer) job(line input.Line) lineJob {
+	if n.byLine {
+		return lineJob{caseNumber: line.Number, line: line}
+	}
+	if line.Skipped != "" {
+		return lineJob{line: line}
+	}
+	n.cases++
+	return lineJob{caseNumber: n.cases, line: line}
+}
+
+// utility to determine how many input lines are processed concurrently, defaulting to GOMAXPROCS.
+func lineWorkers(cfg config.AppConfig) int {
+	if cfg.Workers > 0 {
+		return cfg.Workers
 	}
+	return runtime.GOMAXPROCS(0)
 }
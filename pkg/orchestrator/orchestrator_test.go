@@ -0,0 +1,397 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/input"
+	"github.com/1x-eng/cipherlex/pkg/output"
+	"github.com/1x-eng/cipherlex/pkg/wordmatcher"
+	"github.com/stretchr/testify/assert"
+)
+
+// utility to build a configuration suitable for the examples used in tests.
+func testConfig() config.AppConfig {
+	return config.AppConfig{
+		DictionaryConfig: config.DictionaryConfig{MinWordLength: 2, MaxWordLength: 20, MaxDictionarySize: 100},
+		InputConfig: config.InputConfig{
+			MinLineLength:             2,
+			MaxLineLength:             500,
+			MaxLineCount:              100,
+			MinChunkSize:              10,
+			MaxChunkSize:              100,
+			ChunkSizeAdjustmentFactor: 4,
+		},
+	}
+}
+
+func TestRun_FromReaders(t *testing.T) {
+	report, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("axpaj\napxaj\ndnrbt\npjxdn\nabd\n"),
+		Input:      strings.NewReader("aapxjdnrbtvldptfzbbdbbzxtndrvjblnzjfpvhdhhpxjdnrbt\nzzzz\n"),
+		Config:     testConfig(),
+	})
+
+	assert.NoError(t, err)
+	assert.Len(t, report.Results, 2)
+	assert.Equal(t, 4, report.Results[0].Count)
+	assert.ElementsMatch(t, []string{"axpaj", "apxaj", "dnrbt", "pjxdn"}, report.Results[0].Words)
+	assert.Equal(t, output.LineResult{Case: 2, Line: 2, Count: 0, Words: []string{}}, report.Results[1])
+}
+
+func TestRun_ParallelLinesKeepOrder(t *testing.T) {
+	var lines []string
+	for i := 0; i < 50; i++ {
+		lines = append(lines, strings.Repeat("x", i%7)+"aapxjdnrbt"[:2+i%9])
+	}
+	cfg := testConfig()
+	cfg.Workers = 8
+
+	report, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("axpaj\ndnrbt\naa\nap\n"),
+		Input:      strings.NewReader(strings.Join(lines, "\n")),
+		Config:     cfg,
+	})
+	assert.NoError(t, err)
+
+	cfg.Workers = 1
+	serialReport, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("axpaj\ndnrbt\naa\nap\n"),
+		Input:      strings.NewReader(strings.Join(lines, "\n")),
+		Config:     cfg,
+	})
+	assert.NoError(t, err)
+
+	assert.Len(t, report.Results, len(lines))
+	for i, result := range report.Results {
+		assert.Equal(t, i+1, result.Case, "Results should be ordered by case")
+	}
+	assert.Equal(t, serialReport.Results, report.Results, "Parallel results should equal serial results")
+}
+
+func TestRun_FromPaths(t *testing.T) {
+	report, err := Run(context.Background(), Options{
+		DictionaryPath: "../../examples/1/dict.txt",
+		InputPath:      "../../examples/1/input.txt",
+		Config:         testConfig(),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "../../examples/1/dict.txt", report.Run.DictionaryPath)
+	assert.Equal(t, 4, report.Results[0].Count)
+}
+
+func TestRun_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	report, err := Run(ctx, Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc"),
+		Config:     testConfig(),
+	})
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, report.Results)
+}
+
+func TestRun_CompletedSummary(t *testing.T) {
+	report, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc\nbca\ncab"),
+		Config:     testConfig(),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, output.Summary{Status: output.StatusCompleted, Lines: 3}, report.Summary)
+}
+
+func TestProcessMatches_TimedOut(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), -time.Second)
+	defer cancel()
+
+	results, err := processMatches(ctx, []input.Line{{Number: 1, Text: "abc"}, {Number: 2, Text: "bca"}}, wordmatcher.CompileEntries([]dictionary.Entry{{Word: "abc"}}, testConfig()), nil, 10, testConfig())
+
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, results)
+	assert.Equal(t, output.StatusTimedOut, statusOf(err))
+}
+
+func TestRun_MissingDictionary(t *testing.T) {
+	_, err := Run(context.Background(), Options{Input: strings.NewReader("abc"), Config: testConfig()})
+
+	var loadErr *LoadError
+	assert.True(t, errors.As(err, &loadErr), "Expected a LoadError")
+	assert.Equal(t, "dictionary", loadErr.Source)
+	assert.ErrorIs(t, err, ErrMissingDictionary)
+}
+
+func TestRun_UnreadableInput(t *testing.T) {
+	_, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		InputPath:  "../../test_data/does_not_exist.txt",
+		Config:     testConfig(),
+	})
+
+	var loadErr *LoadError
+	assert.True(t, errors.As(err, &loadErr), "Expected a LoadError")
+	assert.Equal(t, "input", loadErr.Source)
+	assert.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	cfg := testConfig()
+	cfg.Mode = "bogus"
+
+	_, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc"),
+		Config:     cfg,
+	})
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr), "Expected a ConfigError")
+	assert.Equal(t, "mode", configErr.Field)
+}
+
+func TestRun_AhoCorasickRequiresExactMode(t *testing.T) {
+	cfg := testConfig()
+	cfg.Engine = "aho-corasick"
+
+	_, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc"),
+		Config:     cfg,
+	})
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr), "Expected a ConfigError")
+	assert.Equal(t, "engine", configErr.Field)
+}
+
+// TestRun_ExactEngineCombinesMatchers checks that finding the exact words of a scrambled run with a separate exact engine leaves its results unchanged.
+func TestRun_ExactEngineCombinesMatchers(t *testing.T) {
+	run := func(exactEngine string) Report {
+		cfg := testConfig()
+		cfg.ExactEngine = exactEngine
+		report, err := Run(context.Background(), Options{
+			Dictionary: strings.NewReader("axpaj\napxaj\texact\ndnrbt\tscrambled\npjxdn\texact\nabd\n"),
+			Input:      strings.NewReader("aapxjdnrbtvldptfzbbdbbzxtndrvjblnzjfpvhdhhpxjdnrbt\nzzzz\n"),
+			Config:     cfg,
+		})
+		assert.NoError(t, err)
+		return report
+	}
+
+	assert.Equal(t, run("").Results, run("aho-corasick").Results)
+}
+
+// TestRun_InvalidExactEngine checks that an exact engine which does not find exact occurrences is rejected.
+func TestRun_InvalidExactEngine(t *testing.T) {
+	for _, exactEngine := range []string{"fuzzy", "gapped", "unknown"} {
+		cfg := testConfig()
+		cfg.ExactEngine = exactEngine
+		_, err := Run(context.Background(), Options{
+			Dictionary: strings.NewReader("abc\n"),
+			Input:      strings.NewReader("abc"),
+			Config:     cfg,
+		})
+
+		var configErr *ConfigError
+		if assert.True(t, errors.As(err, &configErr), "Expected a ConfigError for exact engine %s", exactEngine) {
+			assert.Equal(t, "exact_engine", configErr.Field)
+		}
+	}
+}
+
+func TestRun_AnnotatedDictionary(t *testing.T) {
+	report, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("axpaj\texact\ndnrbt\n"),
+		Input:      strings.NewReader("aapxjdnrbt\naxpajtbrnd\n"),
+		Config:     testConfig(),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"dnrbt"}, report.Results[0].Words)
+	assert.Equal(t, []string{"axpaj", "dnrbt"}, report.Results[1].Words)
+}
+
+func TestRun_MultipleDictionaries(t *testing.T) {
+	report, err := Run(context.Background(), Options{
+		Dictionaries: []DictionarySource{
+			{Name: "products", Reader: strings.NewReader("axpaj\ndnrbt\n")},
+			{Name: "codenames", Reader: strings.NewReader("dnrbt\nzzzz\n")},
+		},
+		Input:  strings.NewReader("aapxjdnrbt\nqqqq\n"),
+		Config: testConfig(),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []output.DictionaryInfo{{Name: "products"}, {Name: "codenames"}}, report.Run.Dictionaries)
+	assert.Equal(t, output.LineResult{
+		Case:  1,
+		Line:  1,
+		Count: 2,
+		Words: []string{"axpaj", "dnrbt"},
+		ByDictionary: []output.DictionaryResult{
+			{Name: "products", Count: 2, Words: []string{"axpaj", "dnrbt"}},
+			{Name: "codenames", Count: 1, Words: []string{"dnrbt"}},
+		},
+	}, report.Results[0])
+	assert.Equal(t, []output.DictionaryResult{
+		{Name: "products", Count: 0, Words: []string{}},
+		{Name: "codenames", Count: 0, Words: []string{}},
+	}, report.Results[1].ByDictionary)
+}
+
+func TestRun_DuplicateDictionaryNames(t *testing.T) {
+	_, err := Run(context.Background(), Options{
+		Dictionaries: []DictionarySource{
+			{Name: "words", Reader: strings.NewReader("abc")},
+			{Name: "words", Reader: strings.NewReader("def")},
+		},
+		Input:  strings.NewReader("abc"),
+		Config: testConfig(),
+	})
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr), "Expected a ConfigError")
+	assert.Equal(t, "dictionaries", configErr.Field)
+}
+
+func TestRun_Strict(t *testing.T) {
+	cfg := testConfig()
+	cfg.DictionaryConfig.Strict = true
+	_, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("axpaj\naxpaj\n"),
+		Input:      strings.NewReader("aapxjdnrbt\n"),
+		Config:     cfg,
+	})
+
+	var loadErr *LoadError
+	assert.True(t, errors.As(err, &loadErr), "Expected a LoadError")
+	assert.Equal(t, "dictionary", loadErr.Source)
+	var diagErr *diagnostics.Error
+	assert.ErrorAs(t, err, &diagErr)
+}
+
+func TestValidate(t *testing.T) {
+	diags, err := Validate(context.Background(), Options{
+		Dictionaries: []DictionarySource{
+			{Name: "products", Reader: strings.NewReader("axpaj\na\n")},
+			{Name: "codenames", Reader: strings.NewReader("dnrbt\ndnrbt\n")},
+		},
+		Input:  strings.NewReader("aapxjdnrbt\nq\n"),
+		Config: testConfig(),
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []diagnostics.Diagnostic{
+		{Line: 2, Rule: diagnostics.RuleWordTooShort, Value: "a"},
+		{Line: 2, Rule: diagnostics.RuleDuplicateWord, Value: "dnrbt"},
+		{Line: 2, Rule: diagnostics.RuleLineTooShort, Value: "q"},
+	}, diags)
+}
+
+func TestRun_InvalidLongLinePolicy(t *testing.T) {
+	cfg := testConfig()
+	cfg.LongLinePolicy = "wrap"
+
+	_, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc"),
+		Config:     cfg,
+	})
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr), "Expected a ConfigError")
+	assert.Equal(t, "long_line_policy", configErr.Field)
+}
+
+func TestRun_LevenshteinRequiresExactMode(t *testing.T) {
+	cfg := testConfig()
+	cfg.Engine = "fuzzy"
+	cfg.DistanceMetric = "levenshtein"
+
+	_, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc"),
+		Config:     cfg,
+	})
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr), "Expected a ConfigError")
+	assert.Equal(t, "distance_metric", configErr.Field)
+}
+
+func TestRun_GappedRequiresMaxSpan(t *testing.T) {
+	cfg := testConfig()
+	cfg.Engine = "gapped"
+	cfg.MaxSpan = 0
+
+	_, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc"),
+		Config:     cfg,
+	})
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr), "Expected a ConfigError")
+	assert.Equal(t, "max_span", configErr.Field)
+}
+
+func TestRun_GappedMaxSpanShorterThanMaxWordLength(t *testing.T) {
+	cfg := testConfig()
+	cfg.Engine = "gapped"
+	cfg.MaxSpan = 5
+
+	_, err := Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc"),
+		Config:     cfg,
+	})
+
+	var configErr *ConfigError
+	assert.True(t, errors.As(err, &configErr), "Expected a ConfigError")
+	assert.Equal(t, "max_span", configErr.Field)
+
+	cfg.MaxSpan = cfg.MaxWordLength
+	_, err = Run(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc"),
+		Config:     cfg,
+	})
+	assert.NoError(t, err)
+}
+
+func TestRun_Numbering(t *testing.T) {
+	run := func(numbering string) []output.LineResult {
+		cfg := testConfig()
+		cfg.Numbering = numbering
+		report, err := Run(context.Background(), Options{
+			Dictionary: strings.NewReader("axpaj\ndnrbt\n"),
+			Input:      strings.NewReader("x\n\naapxjdnrbt\nzzzz\n"),
+			Config:     cfg,
+		})
+		assert.NoError(t, err)
+		return report.Results
+	}
+
+	assert.Equal(t, []output.LineResult{
+		{Case: 0, Line: 1, Words: []string{}, Skipped: true, Reason: "line-too-short"},
+		{Case: 1, Line: 3, Count: 2, Words: []string{"axpaj", "dnrbt"}},
+		{Case: 2, Line: 4, Count: 0, Words: []string{}},
+	}, run("case"), "Skipped lines are reported without using up a case number")
+	assert.Equal(t, []output.LineResult{
+		{Case: 1, Line: 1, Words: []string{}, Skipped: true, Reason: "line-too-short"},
+		{Case: 3, Line: 3, Count: 2, Words: []string{"axpaj", "dnrbt"}},
+		{Case: 4, Line: 4, Count: 0, Words: []string{}},
+	}, run("line"))
+}
@@ -0,0 +1,199 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/input"
+	"github.com/1x-eng/cipherlex/pkg/output"
+	"github.com/1x-eng/cipherlex/pkg/utils"
+	"github.com/1x-eng/cipherlex/pkg/wordmatcher"
+)
+
+// StdinPath is the input path that reads the input from standard input.
+const StdinPath = "-"
+
+// inFlightLinesPerWorker bounds how many lines may be read ahead of the next line to be written, per worker.
+const inFlightLinesPerWorker = 2
+
+// lineJob is an input line waiting to be matched, along with its case number.
+type lineJob struct {
+	caseNumber int
+	line       input.Line
+}
+
+// sequencedJob is a line job along with the position of its line among the streamed lines.
+type sequencedJob struct {
+	seq int
+	job lineJob
+}
+
+// sequencedResult is a line result along with the position of its line among the streamed lines, used to restore input order.
+type sequencedResult struct {
+	seq    int
+	result output.LineResult
+}
+
+// RunStream loads the dictionary and then streams the input, writing a result per line using the given formatter as soon as it and every line before it are matched.
+// unlike Run it never holds the whole input in memory and ignores MaxLineCount, so it suits unbounded inputs such as logs or standard input.
+// the chunk size is determined from the dictionary alone, since the average line length is not known upfront.
+// once the given context is done no further lines are read, and the results already written stay written. the formatter is then finished
+// with a summary counting them and telling whether the run timed out or was canceled, and that summary is returned along with the context's error.
+func RunStream(ctx context.Context, opts Options, formatter output.Formatter) (output.Summary, error) {
+	if err := validateOptions(opts); err != nil {
+		return output.Summary{}, err
+	}
+
+	dictWords, err := loadDictionaryWords(ctx, &opts)
+	if err != nil {
+		return output.Summary{}, err
+	}
+	r, closeInput, err := openInput(opts)
+	if err != nil {
+		return output.Summary{}, &LoadError{Source: "input", Path: opts.InputPath, Err: err}
+	}
+	defer closeInput()
+
+	chunkSize := determineChunkSize(dictionary.Words(wordmatcher.Entries(dictWords)), nil, opts.Config.InputConfig)
+	if err := formatter.Start(runInfo(opts, chunkSize)); err != nil {
+		return output.Summary{}, err
+	}
+
+	lines, err := streamMatches(ctx, r, dictWords, chunkSize, opts, formatter.WriteResult)
+	summary := output.Summary{Status: statusOf(err), Lines: lines}
+	if finishErr := formatter.Finish(summary); err == nil {
+		err = finishErr
+	}
+	return summary, err
+}
+
+// streams the input lines from the given reader through a pool of line workers, calling emit for every result in input order.
+// the number of lines read ahead of the next line to be emitted is bounded, which bounds memory regardless of input size.
+// returns how many results were emitted.
+func streamMatches(parent context.Context, r io.Reader, dictWords []wordmatcher.CompiledWord, chunkSize int, opts Options, emit func(output.LineResult) error) (int, error) {
+	ctx, cancel := context.WithCancel(parent)
+	defer cancel()
+
+	matcher := wordmatcher.NewMatcherFromCompiled(dictWords, opts.Config, chunkSize)
+	dictNames := dictionaryNames(opts)
+	defer matcher.Close()
+
+	workers := lineWorkers(opts.Config)
+	slots := make(chan struct{}, workers*inFlightLinesPerWorker)
+	jobs := make(chan sequencedJob)
+	results := make(chan sequencedResult)
+
+	// read lines, numbering them and waiting for a free slot before handing each to the workers.
+	readLines := 0
+	readErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		streamer := input.NewStreamProcessor(opts.Config.InputConfig)
+		numberer := newCaseNumberer(opts.Config.Numbering)
+		readErr <- streamer.StreamLines(ctx, r, func(line input.Line) error {
+			job := numberer.job(line)
+			select {
+			case slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			readLines++
+			select {
+			case jobs <- sequencedJob{seq: readLines, job: job}:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		})
+	}()
+
+	var wg sync.WaitGroup
+	for worker := 0; worker < workers; worker++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				result, err := matchLine(ctx, matcher, dictNames, job.job)
+				if err != nil {
+					continue
+				}
+				select {
+				case results <- sequencedResult{seq: job.seq, result: result}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	// reorder results, emitting each one once every result before it has been emitted.
+	pending := make(map[int]output.LineResult)
+	emitted := 0
+	var emitErr error
+	for sequenced := range results {
+		pending[sequenced.seq] = sequenced.result
+		for {
+			next, ok := pending[emitted+1]
+			if !ok || emitErr != nil {
+				break
+			}
+			delete(pending, emitted+1)
+			if emitErr = emit(next); emitErr != nil {
+				cancel()
+				break
+			}
+			emitted++
+			<-slots
+		}
+	}
+
+	if err := <-readErr; emitErr == nil && err != nil {
+		emitErr = err
+	}
+	if emitErr != nil {
+		if parent.Err() != nil {
+			return emitted, parent.Err()
+		}
+		return emitted, emitErr
+	}
+	if emitted < readLines {
+		return emitted, parent.Err()
+	}
+	return emitted, nil
+}
+
+// opens the input of the given options for streaming, from its reader if given, from standard input for StdinPath and from its path otherwise.
+// paths may also name directories or glob patterns, and compressed files and standard input are decompressed.
+// the returned function closes whatever was opened.
+func openInput(opts Options) (io.Reader, func(), error) {
+	switch {
+	case opts.Input != nil:
+		return opts.Input, func() {}, nil
+	case opts.InputPath == StdinPath:
+		stdin, err := openStdin()
+		if err != nil {
+			return nil, nil, err
+		}
+		return stdin, func() { stdin.Close() }, nil
+	case opts.InputPath != "":
+		files, err := utils.OpenFiles(opts.InputPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		return files, func() { files.Close() }, nil
+	default:
+		return nil, nil, ErrMissingInput
+	}
+}
+
+// utility to read standard input, decompressing it when it is compressed.
+func openStdin() (io.ReadCloser, error) {
+	return utils.Decompress(os.Stdin, "")
+}
@@ -0,0 +1,133 @@
+package orchestrator
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/1x-eng/cipherlex/pkg/output"
+	"github.com/stretchr/testify/assert"
+)
+
+// collectingFormatter records everything written to it.
+type collectingFormatter struct {
+	run      output.RunInfo
+	results  []output.LineResult
+	summary  output.Summary
+	failWith error
+}
+
+func (f *collectingFormatter) Start(run output.RunInfo) error {
+	f.run = run
+	return nil
+}
+
+func (f *collectingFormatter) WriteResult(result output.LineResult) error {
+	if f.failWith != nil {
+		return f.failWith
+	}
+	f.results = append(f.results, result)
+	return nil
+}
+
+func (f *collectingFormatter) Finish(summary output.Summary) error {
+	f.summary = summary
+	return nil
+}
+
+func TestRunStream_OrderedResults(t *testing.T) {
+	var lines []string
+	for i := 0; i < 300; i++ {
+		lines = append(lines, strings.Repeat("z", i%11)+"aapxjdnrbt"[:2+i%9])
+	}
+	cfg := testConfig()
+	cfg.Workers = 4
+	cfg.MaxLineCount = 10
+	formatter := &collectingFormatter{}
+
+	summary, err := RunStream(context.Background(), Options{
+		Dictionary: strings.NewReader("axpaj\ndnrbt\naa\nap\n"),
+		Input:      strings.NewReader(strings.Join(lines, "\n")),
+		Config:     cfg,
+	}, formatter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, output.Summary{Status: output.StatusCompleted, Lines: len(lines)}, summary)
+	assert.Equal(t, summary, formatter.summary)
+	assert.Len(t, formatter.results, len(lines), "Streaming should ignore MaxLineCount")
+	for i, result := range formatter.results {
+		assert.Equal(t, i+1, result.Case, "Results should be written in input order")
+	}
+}
+
+func TestRunStream_WriteError(t *testing.T) {
+	writeErr := errors.New("disk full")
+	formatter := &collectingFormatter{failWith: writeErr}
+
+	summary, err := RunStream(context.Background(), Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc\nbca\ncab\n"),
+		Config:     testConfig(),
+	}, formatter)
+
+	assert.ErrorIs(t, err, writeErr)
+	assert.Equal(t, output.StatusFailed, summary.Status)
+	assert.Zero(t, summary.Lines)
+}
+
+func TestRunStream_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	formatter := &collectingFormatter{}
+
+	_, err := RunStream(ctx, Options{
+		Dictionary: strings.NewReader("abc"),
+		Input:      strings.NewReader("abc\nbca\ncab\n"),
+		Config:     testConfig(),
+	}, formatter)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, formatter.results)
+}
+
+func TestRunStream_NumberingByCaseReportsSkippedLines(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workers = 3
+	formatter := &collectingFormatter{}
+
+	_, err := RunStream(context.Background(), Options{
+		Dictionary: strings.NewReader("axpaj\ndnrbt\n"),
+		Input:      strings.NewReader("aapxjdnrbt\nx\n\nzzzz\ndnrbt\n"),
+		Config:     cfg,
+	}, formatter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []output.LineResult{
+		{Case: 1, Line: 1, Count: 2, Words: []string{"axpaj", "dnrbt"}},
+		{Case: 0, Line: 2, Words: []string{}, Skipped: true, Reason: "line-too-short"},
+		{Case: 2, Line: 4, Count: 0, Words: []string{}},
+		{Case: 3, Line: 5, Count: 1, Words: []string{"dnrbt"}},
+	}, formatter.results)
+}
+
+func TestRunStream_NumberingByLine(t *testing.T) {
+	cfg := testConfig()
+	cfg.Workers = 3
+	cfg.Numbering = "line"
+	formatter := &collectingFormatter{}
+
+	_, err := RunStream(context.Background(), Options{
+		Dictionary: strings.NewReader("axpaj\ndnrbt\n"),
+		Input:      strings.NewReader("aapxjdnrbt\nx\n\nzzzz\ndnrbt\n"),
+		Config:     cfg,
+	}, formatter)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []output.LineResult{
+		{Case: 1, Line: 1, Count: 2, Words: []string{"axpaj", "dnrbt"}},
+		{Case: 2, Line: 2, Words: []string{}, Skipped: true, Reason: "line-too-short"},
+		{Case: 4, Line: 4, Count: 0, Words: []string{}},
+		{Case: 5, Line: 5, Count: 1, Words: []string{"dnrbt"}},
+	}, formatter.results)
+}
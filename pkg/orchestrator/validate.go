@@ -0,0 +1,71 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/1x-eng/cipherlex/pkg/diagnostics"
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/input"
+)
+
+// Validate checks the dictionaries and the input of the given options against the configured constraints, without matching anything.
+// it returns a diagnostic for every dictionary word and input line violating a constraint, dictionaries first,
+// and only returns an error when a dictionary or the input cannot be read. dictionaries and input left out of the options are not checked.
+func Validate(ctx context.Context, opts Options) ([]diagnostics.Diagnostic, error) {
+	if err := validateDictionaries(opts.Dictionaries); err != nil {
+		return nil, err
+	}
+	if _, err := input.ParseLongLinePolicy(opts.Config.LongLinePolicy); err != nil {
+		return nil, &ConfigError{Field: "long_line_policy", Err: err}
+	}
+
+	sources := opts.Dictionaries
+	if len(sources) == 0 {
+		sources = []DictionarySource{{Path: opts.DictionaryPath, Reader: opts.Dictionary}}
+	}
+
+	var diags []diagnostics.Diagnostic
+	dictProcessor := dictionary.NewProcessor(opts.Config.DictionaryConfig)
+	for _, source := range sources {
+		var dictDiags []dictionary.Diagnostic
+		var err error
+		switch {
+		case source.Reader != nil:
+			dictDiags, err = dictProcessor.ValidateFrom(ctx, source.Reader)
+		case source.Path != "":
+			dictDiags, err = dictProcessor.Validate(ctx, source.Path)
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, &LoadError{Source: "dictionary", Path: source.Path, Err: err}
+		}
+		diags = append(diags, dictDiags...)
+	}
+
+	inputDiags, err := validateInput(ctx, opts)
+	if err != nil {
+		return nil, &LoadError{Source: "input", Path: opts.InputPath, Err: err}
+	}
+	return append(diags, inputDiags...), nil
+}
+
+// validates the input, from its reader if given, from standard input for StdinPath and from its path otherwise.
+func validateInput(ctx context.Context, opts Options) ([]input.Diagnostic, error) {
+	inputProcessor := input.NewProcessor(opts.Config.InputConfig)
+	switch {
+	case opts.Input != nil:
+		return inputProcessor.ValidateFrom(ctx, opts.Input)
+	case opts.InputPath == StdinPath:
+		stdin, err := openStdin()
+		if err != nil {
+			return nil, err
+		}
+		defer stdin.Close()
+		return inputProcessor.ValidateFrom(ctx, stdin)
+	case opts.InputPath != "":
+		return inputProcessor.Validate(ctx, opts.InputPath)
+	default:
+		return nil, nil
+	}
+}
@@ -0,0 +1,87 @@
+package output
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// csvWordSeparator joins the matched dictionary words of a line result into a single CSV field.
+const csvWordSeparator = ";"
+
+// csvFormatter writes the run as a "#" comment line, followed by a header, one record per line result and the summary as another comment line.
+// the comment lines can be skipped by setting csv.Reader.Comment to '#'.
+// the "line" column holds the line number in the input file, and the "reason" column why a line was skipped, empty for matched lines.
+// with several named dictionaries, every dictionary adds a "<name>.count" and a "<name>.words" column.
+type csvFormatter struct {
+	w            io.Writer
+	writer       *csv.Writer
+	dictionaries []string
+}
+
+// creates a new csvFormatter writing to w.
+func newCSVFormatter(w io.Writer) *csvFormatter {
+	return &csvFormatter{w: w, writer: csv.NewWriter(w)}
+}
+
+// Start writes the run as a JSON comment line, followed by the header.
+func (f *csvFormatter) Start(run RunInfo) error {
+	encoded, err := json.Marshal(run)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(f.w, "# run: %s\n", encoded); err != nil {
+		return err
+	}
+
+	header := []string{"case", "count", "words", "line", "reason"}
+	for _, dictionary := range run.Dictionaries {
+		f.dictionaries = append(f.dictionaries, dictionary.Name)
+		header = append(header, dictionary.Name+".count", dictionary.Name+".words")
+	}
+	return f.write(header)
+}
+
+// WriteResult writes a record for the given line result.
+func (f *csvFormatter) WriteResult(result LineResult) error {
+	record := []string{
+		strconv.Itoa(result.Case),
+		strconv.Itoa(result.Count),
+		strings.Join(result.Words, csvWordSeparator),
+		strconv.Itoa(result.Line),
+		result.Reason,
+	}
+	for _, name := range f.dictionaries {
+		var dictionary DictionaryResult
+		for _, candidate := range result.ByDictionary {
+			if candidate.Name == name {
+				dictionary = candidate
+				break
+			}
+		}
+		record = append(record, strconv.Itoa(dictionary.Count), strings.Join(dictionary.Words, csvWordSeparator))
+	}
+	return f.write(record)
+}
+
+// Finish writes the summary as a JSON comment line.
+func (f *csvFormatter) Finish(summary Summary) error {
+	encoded, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f.w, "# summary: %s\n", encoded)
+	return err
+}
+
+// utility to write a record and flush it, so that records are never interleaved with comment lines.
+func (f *csvFormatter) write(record []string) error {
+	if err := f.writer.Write(record); err != nil {
+		return err
+	}
+	f.writer.Flush()
+	return f.writer.Error()
+}
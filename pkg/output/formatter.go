@@ -0,0 +1,115 @@
+package output
+
+import (
+	"fmt"
+	"io"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+)
+
+// Format names an output format.
+type Format string
+
+const (
+	// FormatText is the human readable "Case #N: count" format.
+	FormatText Format = "text"
+	// FormatJSON is a single JSON document holding the run and all line results.
+	FormatJSON Format = "json"
+	// FormatNDJSON is one JSON object per line, starting with the run.
+	FormatNDJSON Format = "ndjson"
+	// FormatCSV is one CSV record per line result, preceded by the run as comment lines.
+	FormatCSV Format = "csv"
+)
+
+// RunInfo describes the run that produced a set of results.
+// Dictionaries is only set when several named dictionaries are used, listing them in the order of every breakdown.
+type RunInfo struct {
+	DictionaryPath string           `json:"dictionary_path"`
+	Dictionaries   []DictionaryInfo `json:"dictionaries,omitempty"`
+	InputPath      string           `json:"input_path"`
+	ChunkSize      int              `json:"chunk_size"`
+	Config         config.AppConfig `json:"config"`
+}
+
+// DictionaryInfo describes one of several named dictionaries used by a run.
+type DictionaryInfo struct {
+	Name string `json:"name"`
+	Path string `json:"path,omitempty"`
+}
+
+// LineResult holds the outcome of matching a single input line.
+// with several named dictionaries, ByDictionary breaks the result down by dictionary, a word present in several of them
+// counts once towards Count but once per dictionary in the breakdown.
+// Line is the line number of the input line in the input file, and a skipped line carries the rule it violates as its Reason.
+// Case is 0 for a skipped line when cases are numbered rather than lines.
+type LineResult struct {
+	Case         int                `json:"case"`
+	Line         int                `json:"line"`
+	Count        int                `json:"count"`
+	Words        []string           `json:"words"`
+	ByDictionary []DictionaryResult `json:"by_dictionary,omitempty"`
+	Skipped      bool               `json:"skipped,omitempty"`
+	Reason       string             `json:"reason,omitempty"`
+}
+
+// DictionaryResult holds the outcome of matching a single input line against one of several named dictionaries.
+type DictionaryResult struct {
+	Name  string   `json:"name"`
+	Count int      `json:"count"`
+	Words []string `json:"words"`
+}
+
+// Status describes how a run ended.
+type Status string
+
+const (
+	// StatusCompleted means every input line was processed.
+	StatusCompleted Status = "completed"
+	// StatusTimedOut means the run hit its timeout, only the results written so far are available.
+	StatusTimedOut Status = "timed_out"
+	// StatusCanceled means the run was canceled, only the results written so far are available.
+	StatusCanceled Status = "canceled"
+	// StatusFailed means the run ended with an error, only the results written so far are available.
+	StatusFailed Status = "failed"
+)
+
+// Summary describes how a run ended, once all of its line results have been written.
+type Summary struct {
+	Status Status `json:"status"`
+	Lines  int    `json:"lines"`
+}
+
+// Formatter writes a run and its line results to an output stream.
+type Formatter interface {
+	Start(run RunInfo) error
+	WriteResult(result LineResult) error
+	Finish(summary Summary) error
+}
+
+// ParseFormat converts the given name into a Format, an empty name selects FormatText.
+func ParseFormat(name string) (Format, error) {
+	switch Format(name) {
+	case "", FormatText:
+		return FormatText, nil
+	case FormatJSON, FormatNDJSON, FormatCSV:
+		return Format(name), nil
+	default:
+		return "", fmt.Errorf("unknown output format %q, expected one of: %s, %s, %s, %s", name, FormatText, FormatJSON, FormatNDJSON, FormatCSV)
+	}
+}
+
+// creates a new Formatter writing the given format to w.
+func NewFormatter(format Format, w io.Writer) (Formatter, error) {
+	switch format {
+	case "", FormatText:
+		return &textFormatter{w: w}, nil
+	case FormatJSON:
+		return &jsonFormatter{w: w}, nil
+	case FormatNDJSON:
+		return newNDJSONFormatter(w), nil
+	case FormatCSV:
+		return newCSVFormatter(w), nil
+	default:
+		return nil, fmt.Errorf("unknown output format %q", format)
+	}
+}
@@ -0,0 +1,172 @@
+package output
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+var testRun = RunInfo{
+	DictionaryPath: "dict.txt",
+	InputPath:      "input.txt",
+	ChunkSize:      10,
+	Config:         config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: "anagram"}},
+}
+
+var testResults = []LineResult{
+	{Case: 1, Line: 1, Count: 2, Words: []string{"axpaj", "dnrbt"}},
+	{Case: 2, Line: 3, Count: 0, Words: []string{}},
+}
+
+var testSummary = Summary{Status: StatusCompleted, Lines: 2}
+
+// utility to write the test run and results using the given format.
+func writeAll(t *testing.T, format Format) string {
+	return writeRun(t, format, testRun, testResults)
+}
+
+// utility to write the given run and results using the given format.
+func writeRun(t *testing.T, format Format, run RunInfo, results []LineResult) string {
+	var buf bytes.Buffer
+	formatter, err := NewFormatter(format, &buf)
+	assert.NoError(t, err)
+
+	assert.NoError(t, formatter.Start(run))
+	for _, result := range results {
+		assert.NoError(t, formatter.WriteResult(result))
+	}
+	assert.NoError(t, formatter.Finish(testSummary))
+	return buf.String()
+}
+
+func TestTextFormatter(t *testing.T) {
+	assert.Equal(t, "Case #1: 2\nCase #2: 0\n", writeAll(t, FormatText))
+}
+
+func TestJSONFormatter(t *testing.T) {
+	var document jsonDocument
+	assert.NoError(t, json.Unmarshal([]byte(writeAll(t, FormatJSON)), &document))
+
+	assert.Equal(t, testRun, document.Run)
+	assert.Equal(t, testResults, document.Results)
+	assert.Equal(t, testSummary, document.Summary)
+}
+
+func TestJSONFormatter_NoResults(t *testing.T) {
+	var buf bytes.Buffer
+	formatter, err := NewFormatter(FormatJSON, &buf)
+	assert.NoError(t, err)
+	assert.NoError(t, formatter.Start(testRun))
+	assert.NoError(t, formatter.Finish(testSummary))
+
+	var document jsonDocument
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &document))
+	assert.Empty(t, document.Results)
+	assert.Equal(t, testSummary, document.Summary)
+}
+
+func TestNDJSONFormatter(t *testing.T) {
+	lines := strings.Split(strings.TrimSpace(writeAll(t, FormatNDJSON)), "\n")
+	assert.Len(t, lines, 4, "Expected one run record, one record per result and one summary record")
+
+	var run ndjsonRun
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &run))
+	assert.Equal(t, "run", run.Type)
+	assert.Equal(t, testRun, run.RunInfo)
+
+	var result ndjsonResult
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &result))
+	assert.Equal(t, "result", result.Type)
+	assert.Equal(t, testResults[0], result.LineResult)
+
+	var summary ndjsonSummary
+	assert.NoError(t, json.Unmarshal([]byte(lines[3]), &summary))
+	assert.Equal(t, "summary", summary.Type)
+	assert.Equal(t, testSummary, summary.Summary)
+}
+
+func TestCSVFormatter(t *testing.T) {
+	reader := csv.NewReader(strings.NewReader(writeAll(t, FormatCSV)))
+	reader.Comment = '#'
+
+	records, err := reader.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"case", "count", "words", "line", "reason"},
+		{"1", "2", "axpaj;dnrbt", "1", ""},
+		{"2", "0", "", "3", ""},
+	}, records)
+}
+
+func TestParseFormat(t *testing.T) {
+	format, err := ParseFormat("")
+	assert.NoError(t, err)
+	assert.Equal(t, FormatText, format)
+
+	_, err = ParseFormat("xml")
+	assert.Error(t, err)
+}
+
+var testDictionariesRun = RunInfo{
+	Dictionaries: []DictionaryInfo{{Name: "products", Path: "products.txt"}, {Name: "codenames", Path: "codenames.txt"}},
+	InputPath:    "input.txt",
+	ChunkSize:    10,
+}
+
+var testDictionariesResults = []LineResult{
+	{Case: 1, Line: 1, Count: 2, Words: []string{"axpaj", "dnrbt"}, ByDictionary: []DictionaryResult{
+		{Name: "products", Count: 2, Words: []string{"axpaj", "dnrbt"}},
+		{Name: "codenames", Count: 1, Words: []string{"dnrbt"}},
+	}},
+}
+
+func TestFormatters_ByDictionary(t *testing.T) {
+	assert.Equal(t, "Case #1: 2 (products: 2, codenames: 1)\n", writeRun(t, FormatText, testDictionariesRun, testDictionariesResults))
+
+	var document jsonDocument
+	assert.NoError(t, json.Unmarshal([]byte(writeRun(t, FormatJSON, testDictionariesRun, testDictionariesResults)), &document))
+	assert.Equal(t, testDictionariesRun, document.Run)
+	assert.Equal(t, testDictionariesResults, document.Results)
+
+	reader := csv.NewReader(strings.NewReader(writeRun(t, FormatCSV, testDictionariesRun, testDictionariesResults)))
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"case", "count", "words", "line", "reason", "products.count", "products.words", "codenames.count", "codenames.words"},
+		{"1", "2", "axpaj;dnrbt", "1", "", "2", "axpaj;dnrbt", "1", "dnrbt"},
+	}, records)
+}
+
+func TestFormatters_Skipped(t *testing.T) {
+	results := []LineResult{
+		{Case: 1, Line: 1, Count: 1, Words: []string{"axpaj"}},
+		{Case: 2, Line: 2, Words: []string{}, Skipped: true, Reason: "line-too-short"},
+		{Line: 3, Words: []string{}, Skipped: true, Reason: "line-too-long"},
+	}
+	assert.Equal(t, "Case #1: 1\nCase #2: skipped: line-too-short\nLine #3: skipped: line-too-long\n", writeRun(t, FormatText, testRun, results))
+
+	var document jsonDocument
+	assert.NoError(t, json.Unmarshal([]byte(writeRun(t, FormatJSON, testRun, results)), &document))
+	assert.Equal(t, results, document.Results)
+
+	reader := csv.NewReader(strings.NewReader(writeRun(t, FormatCSV, testRun, results)))
+	reader.Comment = '#'
+	records, err := reader.ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"2", "0", "", "2", "line-too-short"}, records[2])
+}
+
+func TestParseNumbering(t *testing.T) {
+	numbering, err := ParseNumbering("")
+	assert.NoError(t, err)
+	assert.Equal(t, NumberingCase, numbering)
+
+	_, err = ParseNumbering("page")
+	assert.Error(t, err)
+}
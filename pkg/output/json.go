@@ -0,0 +1,104 @@
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// jsonFormatter writes the run, every line result and the summary as a single JSON document.
+// the document is written incrementally, so results never have to be buffered.
+type jsonFormatter struct {
+	w       io.Writer
+	results int
+}
+
+// jsonDocument is the document written by jsonFormatter.
+type jsonDocument struct {
+	Run     RunInfo      `json:"run"`
+	Results []LineResult `json:"results"`
+	Summary Summary      `json:"summary"`
+}
+
+// Start opens the document and writes the run.
+func (f *jsonFormatter) Start(run RunInfo) error {
+	f.results = 0
+	encoded, err := json.MarshalIndent(run, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(f.w, "{\n  \"run\": %s,\n  \"results\": [", encoded)
+	return err
+}
+
+// WriteResult writes the given line result as the next element of the results array.
+func (f *jsonFormatter) WriteResult(result LineResult) error {
+	encoded, err := json.MarshalIndent(result, "    ", "  ")
+	if err != nil {
+		return err
+	}
+	separator := ","
+	if f.results == 0 {
+		separator = ""
+	}
+	f.results++
+	_, err = fmt.Fprintf(f.w, "%s\n    %s", separator, encoded)
+	return err
+}
+
+// Finish closes the results array, writes the given summary and closes the document.
+func (f *jsonFormatter) Finish(summary Summary) error {
+	encoded, err := json.MarshalIndent(summary, "  ", "  ")
+	if err != nil {
+		return err
+	}
+	closing := "\n  ]"
+	if f.results == 0 {
+		closing = "]"
+	}
+	_, err = fmt.Fprintf(f.w, "%s,\n  \"summary\": %s\n}\n", closing, encoded)
+	return err
+}
+
+// ndjsonFormatter writes the run, every line result and then the summary as one JSON object per line.
+type ndjsonFormatter struct {
+	encoder *json.Encoder
+}
+
+// ndjsonRun is the first record written by ndjsonFormatter.
+type ndjsonRun struct {
+	Type string `json:"type"`
+	RunInfo
+}
+
+// ndjsonResult is the record written by ndjsonFormatter for every line result.
+type ndjsonResult struct {
+	Type string `json:"type"`
+	LineResult
+}
+
+// ndjsonSummary is the last record written by ndjsonFormatter.
+type ndjsonSummary struct {
+	Type string `json:"type"`
+	Summary
+}
+
+// creates a new ndjsonFormatter writing to w.
+func newNDJSONFormatter(w io.Writer) *ndjsonFormatter {
+	return &ndjsonFormatter{encoder: json.NewEncoder(w)}
+}
+
+// Start writes the run record.
+func (f *ndjsonFormatter) Start(run RunInfo) error {
+	return f.encoder.Encode(ndjsonRun{Type: "run", RunInfo: run})
+}
+
+// WriteResult writes a record for the given line result.
+func (f *ndjsonFormatter) WriteResult(result LineResult) error {
+	return f.encoder.Encode(ndjsonResult{Type: "result", LineResult: result})
+}
+
+// Finish writes the summary record.
+func (f *ndjsonFormatter) Finish(summary Summary) error {
+	return f.encoder.Encode(ndjsonSummary{Type: "summary", Summary: summary})
+}
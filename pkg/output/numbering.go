@@ -0,0 +1,25 @@
+package output
+
+import "fmt"
+
+// Numbering decides what the case number of a line result refers to.
+type Numbering string
+
+const (
+	// NumberingCase numbers the loaded input lines 1, 2, 3... in order, skipped lines get a result with case number 0 so they use up none.
+	NumberingCase Numbering = "case"
+	// NumberingLine numbers every result after its line in the input file, skipped lines included.
+	NumberingLine Numbering = "line"
+)
+
+// ParseNumbering converts the given name into a Numbering, an empty name selects NumberingCase.
+func ParseNumbering(name string) (Numbering, error) {
+	switch Numbering(name) {
+	case "", NumberingCase:
+		return NumberingCase, nil
+	case NumberingLine:
+		return NumberingLine, nil
+	default:
+		return "", fmt.Errorf("unknown numbering %q, expected one of: %s, %s", name, NumberingCase, NumberingLine)
+	}
+}
@@ -0,0 +1,46 @@
+package output
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// textFormatter writes one "Case #N: count" line per line result, followed by the count of every dictionary when several are used.
+// skipped lines are written as "Case #N: skipped: reason", or as "Line #N: skipped: reason" when they have no case number.
+type textFormatter struct {
+	w io.Writer
+}
+
+// Start writes nothing, the text format does not include the run.
+func (f *textFormatter) Start(run RunInfo) error {
+	return nil
+}
+
+// WriteResult writes the count of the given line result, e.g. "Case #1: 3 (products: 2, profanity: 1)".
+func (f *textFormatter) WriteResult(result LineResult) error {
+	if result.Skipped && result.Case == 0 {
+		_, err := fmt.Fprintf(f.w, "Line #%d: skipped: %s\n", result.Line, result.Reason)
+		return err
+	}
+	if result.Skipped {
+		_, err := fmt.Fprintf(f.w, "Case #%d: skipped: %s\n", result.Case, result.Reason)
+		return err
+	}
+	if len(result.ByDictionary) == 0 {
+		_, err := fmt.Fprintf(f.w, "Case #%d: %d\n", result.Case, result.Count)
+		return err
+	}
+
+	counts := make([]string, len(result.ByDictionary))
+	for i, dictionary := range result.ByDictionary {
+		counts[i] = fmt.Sprintf("%s: %d", dictionary.Name, dictionary.Count)
+	}
+	_, err := fmt.Fprintf(f.w, "Case #%d: %d (%s)\n", result.Case, result.Count, strings.Join(counts, ", "))
+	return err
+}
+
+// Finish writes nothing, every line result has already been written and the status is left to the caller to report.
+func (f *textFormatter) Finish(summary Summary) error {
+	return nil
+}
@@ -0,0 +1,65 @@
+package utils
+
+// Automaton is an Aho–Corasick automaton over the words of a Trie, built by BuildAutomaton.
+// it keeps its own states alongside the nodes of the Trie, so the Trie itself holds no automaton state.
+type Automaton struct {
+	Root *AutomatonState
+}
+
+// AutomatonState is the state of an Automaton once the runes of a prefix of its words are read.
+type AutomatonState struct {
+	Node     *Node           // node of the prefix in the Trie
+	Output   *AutomatonState // nearest word state along the failure links, nil if there is none
+	Depth    int             // number of runes from the root to this state
+	fail     *AutomatonState // state of the longest proper suffix of this state's prefix that is also a prefix in the Trie
+	children map[rune]*AutomatonState
+}
+
+// BuildAutomaton builds an Aho–Corasick automaton over the words of the Trie, by setting the failure and output links of a state per node.
+// the Trie must not be modified afterwards, or the automaton has to be built again.
+func (t *Trie) BuildAutomaton() *Automaton {
+	root := newAutomatonState(t.Root, 0)
+
+	// states are linked breadth first, so the failure link of a state always points to an already linked, shallower state.
+	queue := []*AutomatonState{root}
+	for len(queue) > 0 {
+		state := queue[0]
+		queue = queue[1:]
+
+		for r, node := range state.Node.Children {
+			child := newAutomatonState(node, state.Depth+1)
+			state.children[r] = child
+			child.fail = root
+			for fail := state.fail; fail != nil; fail = fail.fail {
+				if next, ok := fail.children[r]; ok {
+					child.fail = next
+					break
+				}
+			}
+			if child.fail.Node.IsWord {
+				child.Output = child.fail
+			} else {
+				child.Output = child.fail.Output
+			}
+			queue = append(queue, child)
+		}
+	}
+
+	Log.Debug("Built Aho–Corasick automaton")
+	return &Automaton{Root: root}
+}
+
+// creates a new AutomatonState for the given Trie node, at the given depth, with no links yet.
+func newAutomatonState(node *Node, depth int) *AutomatonState {
+	return &AutomatonState{Node: node, Depth: depth, children: make(map[rune]*AutomatonState, len(node.Children))}
+}
+
+// Next returns the state of the automaton after reading the given rune in the given state, following failure links as needed.
+func (a *Automaton) Next(state *AutomatonState, r rune) *AutomatonState {
+	for ; state != nil; state = state.fail {
+		if next, ok := state.children[r]; ok {
+			return next
+		}
+	}
+	return a.Root
+}
@@ -0,0 +1,28 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestAutomaton_FindsOverlappingWords checks the failure and output links on the classic he/she/his/hers example.
+func TestAutomaton_FindsOverlappingWords(t *testing.T) {
+	trie := NewTrie()
+	for _, word := range []string{"he", "she", "his", "hers"} {
+		trie.InsertWithSource(word, word)
+	}
+	automaton := trie.BuildAutomaton()
+
+	var found []string
+	state := automaton.Root
+	for _, r := range "ushers" {
+		state = automaton.Next(state, r)
+		for output := state; output != nil; output = output.Output {
+			if output.Node.IsWord {
+				found = append(found, output.Node.Words...)
+			}
+		}
+	}
+	assert.Equal(t, []string{"she", "he", "hers"}, found)
+}
@@ -10,8 +10,8 @@ type ChunkSizeCalculator struct {
 func LongestWordLength(words []string) int {
 	maxLength := 0
 	for _, word := range words {
-		if len(word) > maxLength {
-			maxLength = len(word)
+		if length := GraphemeCount(word); length > maxLength {
+			maxLength = length
 		}
 	}
 
@@ -26,7 +26,7 @@ func LongestWordLength(words []string) int {
 func CalculateAverageLineLength(lines []string) int {
 	totalLength := 0
 	for _, line := range lines {
-		totalLength += len(line)
+		totalLength += GraphemeCount(line)
 	}
 	if len(lines) == 0 {
 		return 0 // Avoid division by zero
@@ -0,0 +1,279 @@
+package utils
+
+import "fmt"
+
+// WordTrie stores words along with the source words they were inserted for, it is implemented by Trie and CompactTrie.
+type WordTrie interface {
+	Insert(word string)
+	InsertWithSource(word, source string)
+	Delete(word string) bool
+	Find(word string) bool
+	Lookup(word string) []string
+	HasPrefix(prefix string) bool
+	WordsWithPrefix(prefix string) []string
+	Walk(fn func(word string, sources []string) bool)
+	Len() int
+	Stats() TrieStats
+}
+
+// TrieImpl selects the WordTrie implementation a matcher stores its keys in.
+type TrieImpl string
+
+const (
+	// TrieMap is Trie, every node holds a map of its children and can be turned into an Aho–Corasick automaton.
+	TrieMap TrieImpl = "map"
+	// TrieCompact is CompactTrie, every node holds a sorted array of its children, which takes several times less memory.
+	TrieCompact TrieImpl = "compact"
+)
+
+// ParseTrieImpl converts the given name into a TrieImpl, an empty name selects TrieMap.
+func ParseTrieImpl(name string) (TrieImpl, error) {
+	switch TrieImpl(name) {
+	case "", TrieMap:
+		return TrieMap, nil
+	case TrieCompact:
+		return TrieCompact, nil
+	default:
+		return "", fmt.Errorf("unknown trie implementation %q, expected one of: %s, %s", name, TrieMap, TrieCompact)
+	}
+}
+
+// NewWordTrie creates a new, empty WordTrie of the given implementation.
+func NewWordTrie(impl TrieImpl) WordTrie {
+	if impl == TrieCompact {
+		return NewCompactTrie()
+	}
+	return NewTrie()
+}
+
+// compactEdge links a node of a CompactTrie to one of its children.
+type compactEdge struct {
+	r     rune
+	child uint32
+}
+
+// compactNode is a node of a CompactTrie, its children are sorted by rune and found by binary search.
+type compactNode struct {
+	edges   []compactEdge
+	sources int32 // index of the node's source words in CompactTrie.sources, -1 unless a word ends at the node
+}
+
+// CompactTrie is a WordTrie storing its nodes in a single slice, each with a sorted array of edges to its children instead of a map,
+// and the source words of all its words in another slice. it takes several times less memory than Trie for large dictionaries,
+// at the cost of a binary search per rune when walking it. nodes and source words removed by Delete are reused by later inserts.
+type CompactTrie struct {
+	nodes       []compactNode
+	sources     [][]string
+	freeNodes   []uint32
+	freeSources []int32
+	size        int
+}
+
+// NewCompactTrie creates a new CompactTrie instance.
+func NewCompactTrie() *CompactTrie {
+	return &CompactTrie{nodes: []compactNode{{sources: -1}}}
+}
+
+// Insert inserts a word into the CompactTrie.
+func (t *CompactTrie) Insert(word string) {
+	t.insert(word)
+}
+
+// InsertWithSource inserts a word into the CompactTrie, recording the given source word on its node.
+// a source word is recorded once, however often it is inserted.
+func (t *CompactTrie) InsertWithSource(word, source string) {
+	idx := t.insert(word)
+	sources := &t.sources[idx]
+	for _, existing := range *sources {
+		if existing == source {
+			return
+		}
+	}
+	*sources = append(*sources, source)
+}
+
+// utility to insert a word into the CompactTrie, returning the index of its source words.
+func (t *CompactTrie) insert(word string) int32 {
+	node := uint32(0)
+	for _, r := range word {
+		edges := t.nodes[node].edges
+		i := searchEdges(edges, r)
+		if i < len(edges) && edges[i].r == r {
+			node = edges[i].child
+			continue
+		}
+
+		child := t.newNode()
+		edges = append(edges, compactEdge{})
+		copy(edges[i+1:], edges[i:])
+		edges[i] = compactEdge{r: r, child: child}
+		t.nodes[node].edges = edges
+		node = child
+	}
+	if t.nodes[node].sources < 0 {
+		t.nodes[node].sources = t.newSources()
+		t.size++
+	}
+	return t.nodes[node].sources
+}
+
+// utility to add a node without edges or word, reusing one removed by Delete if there is any, returning its index.
+func (t *CompactTrie) newNode() uint32 {
+	if n := len(t.freeNodes); n > 0 {
+		node := t.freeNodes[n-1]
+		t.freeNodes = t.freeNodes[:n-1]
+		return node
+	}
+	t.nodes = append(t.nodes, compactNode{sources: -1})
+	return uint32(len(t.nodes) - 1)
+}
+
+// utility to add an empty list of source words, reusing one removed by Delete if there is any, returning its index.
+func (t *CompactTrie) newSources() int32 {
+	if n := len(t.freeSources); n > 0 {
+		sources := t.freeSources[n-1]
+		t.freeSources = t.freeSources[:n-1]
+		return sources
+	}
+	t.sources = append(t.sources, nil)
+	return int32(len(t.sources) - 1)
+}
+
+// Delete removes a word and its source words from the CompactTrie, returning whether it was there.
+// nodes left without a word below them are unlinked from their parents, and reused by later inserts.
+func (t *CompactTrie) Delete(word string) bool {
+	path := []uint32{0}
+	runes := []rune(word)
+	for _, r := range runes {
+		edges := t.nodes[path[len(path)-1]].edges
+		i := searchEdges(edges, r)
+		if i == len(edges) || edges[i].r != r {
+			return false
+		}
+		path = append(path, edges[i].child)
+	}
+	node := path[len(path)-1]
+	sources := t.nodes[node].sources
+	if sources < 0 {
+		return false
+	}
+	t.sources[sources] = nil
+	t.freeSources = append(t.freeSources, sources)
+	t.nodes[node].sources = -1
+	t.size--
+
+	for i := len(path) - 1; i > 0 && t.nodes[path[i]].sources < 0 && len(t.nodes[path[i]].edges) == 0; i-- {
+		parent := &t.nodes[path[i-1]]
+		j := searchEdges(parent.edges, runes[i-1])
+		parent.edges = append(parent.edges[:j], parent.edges[j+1:]...)
+		t.freeNodes = append(t.freeNodes, path[i])
+	}
+	return true
+}
+
+// Find checks if a word is in the CompactTrie.
+func (t *CompactTrie) Find(word string) bool {
+	node, ok := t.node(word)
+	return ok && t.nodes[node].sources >= 0
+}
+
+// Lookup returns the source words recorded for a word, in insertion order, or nil if the word is not in the CompactTrie.
+func (t *CompactTrie) Lookup(word string) []string {
+	node, ok := t.node(word)
+	if !ok || t.nodes[node].sources < 0 {
+		return nil
+	}
+	return t.sources[t.nodes[node].sources]
+}
+
+// utility to walk the CompactTrie along a word, returning the index of the node it ends at and whether there is one.
+func (t *CompactTrie) node(word string) (uint32, bool) {
+	node := uint32(0)
+	for _, r := range word {
+		edges := t.nodes[node].edges
+		i := searchEdges(edges, r)
+		if i == len(edges) || edges[i].r != r {
+			return 0, false
+		}
+		node = edges[i].child
+	}
+	return node, true
+}
+
+// utility to find the index of the first of the given sorted edges whose rune is not less than r, or len(edges) if there is none.
+// this is sort.Search written out, so the hot path of a lookup does not go through a closure.
+func searchEdges(edges []compactEdge, r rune) int {
+	lo, hi := 0, len(edges)
+	for lo < hi {
+		mid := int(uint(lo+hi) >> 1)
+		if edges[mid].r < r {
+			lo = mid + 1
+		} else {
+			hi = mid
+		}
+	}
+	return lo
+}
+
+// HasPrefix checks if any word in the CompactTrie starts with the given prefix, every word starts with the empty prefix.
+func (t *CompactTrie) HasPrefix(prefix string) bool {
+	if prefix == "" {
+		return t.size > 0
+	}
+	_, ok := t.node(prefix)
+	return ok
+}
+
+// WordsWithPrefix returns the words in the CompactTrie starting with the given prefix, ordered by their runes.
+func (t *CompactTrie) WordsWithPrefix(prefix string) []string {
+	node, ok := t.node(prefix)
+	if !ok {
+		return nil
+	}
+	var words []string
+	t.walk(node, []rune(prefix), func(word string, _ []string) bool {
+		words = append(words, word)
+		return true
+	})
+	return words
+}
+
+// Walk calls fn with every word in the CompactTrie and its source words, ordered by their runes, until fn returns false.
+// the CompactTrie must not be changed during the walk.
+func (t *CompactTrie) Walk(fn func(word string, sources []string) bool) {
+	t.walk(0, nil, fn)
+}
+
+// utility to walk the words below a node, the given prefix being the runes leading to it, returning false once fn did.
+// edges are sorted, so words are visited in order without sorting.
+func (t *CompactTrie) walk(node uint32, prefix []rune, fn func(word string, sources []string) bool) bool {
+	if sources := t.nodes[node].sources; sources >= 0 && !fn(string(prefix), t.sources[sources]) {
+		return false
+	}
+	for _, e := range t.nodes[node].edges {
+		if !t.walk(e.child, append(prefix, e.r), fn) {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of words in the CompactTrie.
+func (t *CompactTrie) Len() int {
+	return t.size
+}
+
+// Stats returns the number of words and nodes of the CompactTrie, and the depth of its words.
+// nodes removed by Delete and not reused yet are not counted.
+func (t *CompactTrie) Stats() TrieStats {
+	var stats TrieStats
+	var visit func(node uint32, depth int)
+	visit = func(node uint32, depth int) {
+		stats.add(depth, t.nodes[node].sources >= 0, len(t.nodes[node].edges) == 0)
+		for _, e := range t.nodes[node].edges {
+			visit(e.child, depth+1)
+		}
+	}
+	visit(0, 0)
+	return stats
+}
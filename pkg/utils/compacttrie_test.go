@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"fmt"
+	"math/rand"
+	"runtime"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCompactTrie_LookupReturnsSourceWords checks that every source word inserted under a word is returned once, in insertion order.
+func TestCompactTrie_LookupReturnsSourceWords(t *testing.T) {
+	trie := NewCompactTrie()
+	trie.InsertWithSource("abt", "tab")
+	trie.InsertWithSource("abt", "bat")
+	trie.InsertWithSource("abt", "tab")
+	trie.InsertWithSource("act", "cat")
+	trie.Insert("ab")
+
+	assert.Equal(t, []string{"tab", "bat"}, trie.Lookup("abt"))
+	assert.Equal(t, []string{"cat"}, trie.Lookup("act"))
+	assert.Empty(t, trie.Lookup("ab"), "Words inserted without a source carry none")
+	assert.True(t, trie.Find("ab"))
+	assert.Nil(t, trie.Lookup("a"), "Prefixes are not words")
+	assert.Nil(t, trie.Lookup("xyz"))
+}
+
+// TestCompactTrie_LookupAfterGrowth checks that no source word is lost while inserting words grows the list of source words.
+func TestCompactTrie_LookupAfterGrowth(t *testing.T) {
+	trie := NewCompactTrie()
+	words := randomWords(5000)
+	for _, word := range words {
+		trie.InsertWithSource(word, "source of "+word)
+	}
+	for _, word := range words {
+		assert.Equal(t, []string{"source of " + word}, trie.Lookup(word), "word=%q", word)
+	}
+}
+
+// TestCompactTrie_EqualsTrie checks that both implementations find, look up, walk and delete the same words, including multi-byte ones.
+func TestCompactTrie_EqualsTrie(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	alphabet := []rune("abcdeéñ日本")
+	word := func() string {
+		runes := make([]rune, 1+rng.Intn(6))
+		for i := range runes {
+			runes[i] = alphabet[rng.Intn(len(alphabet))]
+		}
+		return string(runes)
+	}
+
+	for iteration := 0; iteration < 50; iteration++ {
+		tries := []WordTrie{NewTrie(), NewCompactTrie()}
+		for i := 0; i < 1+rng.Intn(40); i++ {
+			w, source := word(), word()
+			for _, trie := range tries {
+				trie.InsertWithSource(w, source)
+			}
+		}
+		for i := 0; i < 100; i++ {
+			w := word()
+			assert.Equal(t, tries[0].Find(w), tries[1].Find(w), "word=%q", w)
+			assert.Equal(t, tries[0].Lookup(w), tries[1].Lookup(w), "word=%q", w)
+			assert.Equal(t, tries[0].HasPrefix(w[:1]), tries[1].HasPrefix(w[:1]), "prefix=%q", w[:1])
+			assert.Equal(t, tries[0].Delete(w), tries[1].Delete(w), "word=%q", w)
+		}
+		assert.Equal(t, walk(tries[0]), walk(tries[1]))
+		assert.Equal(t, tries[0].Stats(), tries[1].Stats())
+	}
+}
+
+// utility to collect the words of a trie, with their source words, in the order they are walked.
+func walk(trie WordTrie) []string {
+	var words []string
+	trie.Walk(func(word string, sources []string) bool {
+		words = append(words, fmt.Sprint(word, sources))
+		return true
+	})
+	return words
+}
+
+func TestParseTrieImpl(t *testing.T) {
+	impl, err := ParseTrieImpl("")
+	assert.NoError(t, err)
+	assert.Equal(t, TrieMap, impl)
+	impl, err = ParseTrieImpl("compact")
+	assert.NoError(t, err)
+	assert.Equal(t, TrieCompact, impl)
+	_, err = ParseTrieImpl("radix")
+	assert.Error(t, err)
+}
+
+// utility to generate the given number of random lowercase words, 4 to 12 letters long.
+func randomWords(n int) []string {
+	rng := rand.New(rand.NewSource(1))
+	words := make([]string, n)
+	for i := range words {
+		b := make([]byte, 4+rng.Intn(9))
+		for j := range b {
+			b[j] = byte('a' + rng.Intn(26))
+		}
+		words[i] = string(b)
+	}
+	return words
+}
+
+// BenchmarkTrieMemory reports the heap taken by each implementation once it holds a dictionary, in bytes per word.
+func BenchmarkTrieMemory(b *testing.B) {
+	for _, impl := range []TrieImpl{TrieMap, TrieCompact} {
+		for _, size := range []int{10000, 100000} {
+			words := randomWords(size)
+			b.Run(fmt.Sprintf("%s/%d", impl, size), func(b *testing.B) {
+				var before, after runtime.MemStats
+				var trie WordTrie
+				for i := 0; i < b.N; i++ {
+					runtime.GC()
+					runtime.ReadMemStats(&before)
+					trie = NewWordTrie(impl)
+					for _, w := range words {
+						trie.InsertWithSource(w, w)
+					}
+					runtime.GC()
+					runtime.ReadMemStats(&after)
+				}
+				b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc)/float64(len(words)), "B/word")
+				runtime.KeepAlive(trie)
+			})
+		}
+	}
+}
+
+// BenchmarkTrieLookup compares the lookup speed of each implementation, half of the looked up words being absent.
+func BenchmarkTrieLookup(b *testing.B) {
+	words := randomWords(100000)
+	for _, impl := range []TrieImpl{TrieMap, TrieCompact} {
+		trie := NewWordTrie(impl)
+		for _, w := range words[:len(words)/2] {
+			trie.InsertWithSource(w, w)
+		}
+		b.Run(string(impl), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				trie.Lookup(words[i%len(words)])
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package utils
+
+import (
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+var (
+	gzipMagic        = []byte{0x1f, 0x8b}
+	bzip2Magic       = []byte("BZh")
+	bzip2BlockMagic  = []byte{0x31, 0x41, 0x59, 0x26, 0x53, 0x59} // digits of pi, starting the first block of a bzip2 stream
+	bzip2EndOfStream = []byte{0x17, 0x72, 0x45, 0x38, 0x50, 0x90} // digits of sqrt(pi), ending an empty bzip2 stream
+)
+
+// Compression names a compression format the standard library can read.
+type Compression string
+
+const (
+	// CompressionNone is plain, uncompressed data.
+	CompressionNone Compression = "none"
+	// CompressionGzip is gzip, detected by its magic bytes or the .gz extension.
+	CompressionGzip Compression = "gzip"
+	// CompressionBzip2 is bzip2, detected by its magic bytes or the .bz2 extension.
+	CompressionBzip2 Compression = "bzip2"
+	// CompressionZlib is zlib, detected by the .zlib or .zz extension only, since its two byte header is easily mistaken for text.
+	CompressionZlib Compression = "zlib"
+)
+
+// ExpandPaths resolves the given path into the files it names, in lexical order.
+// a directory names every regular file below it, and a glob pattern every file or directory it matches.
+// an error wrapping fs.ErrNotExist is returned when nothing matches.
+func ExpandPaths(path string) ([]string, error) {
+	matches := []string{path}
+	if strings.ContainsAny(path, "*?[") {
+		var err error
+		matches, err = filepath.Glob(path)
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", path, err)
+		}
+		if len(matches) == 0 {
+			return nil, &fs.PathError{Op: "glob", Path: path, Err: fs.ErrNotExist}
+		}
+	}
+
+	var files []string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, match)
+			continue
+		}
+		err = filepath.WalkDir(match, func(file string, entry fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if entry.Type().IsRegular() {
+				files = append(files, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.Strings(files)
+
+	Log.WithFields(map[string]interface{}{
+		"path":      path,
+		"fileCount": len(files),
+	}).Debug("Expanded path into files")
+
+	return files, nil
+}
+
+// OpenFiles opens every file named by the given path, as resolved by ExpandPaths, as a single reader decompressing each file as needed.
+// a line break is inserted between files that do not end with one, so the last line of a file never runs into the first line of the next.
+// files are opened one at a time as they are read, closing the reader closes the file currently open.
+func OpenFiles(path string) (io.ReadCloser, error) {
+	files, err := ExpandPaths(path)
+	if err != nil {
+		return nil, err
+	}
+	return &filesReader{files: files}, nil
+}
+
+// OpenFile opens a single file, decompressing it as needed, closing the reader closes the file.
+func OpenFile(path string) (io.ReadCloser, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	decompressed, err := Decompress(file, path)
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to decompress %s: %w", path, err)
+	}
+	return &decompressedFile{ReadCloser: decompressed, file: file}, nil
+}
+
+// decompressedFile is a file read through its decompressor.
+type decompressedFile struct {
+	io.ReadCloser
+	file *os.File
+}
+
+// Close closes the decompressor and then the file.
+func (f *decompressedFile) Close() error {
+	err := f.ReadCloser.Close()
+	if closeErr := f.file.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// Decompress wraps the given reader so that compressed data is decompressed, detecting the compression from the given name and the data itself.
+func Decompress(r io.Reader, name string) (io.ReadCloser, error) {
+	buffered := bufio.NewReader(r)
+	header, _ := buffered.Peek(10)
+
+	compression := DetectCompression(name, header)
+	Log.WithFields(map[string]interface{}{
+		"name":        name,
+		"compression": compression,
+	}).Debug("Detected compression")
+
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewReader(buffered)
+	case CompressionBzip2:
+		return io.NopCloser(bzip2.NewReader(buffered)), nil
+	case CompressionZlib:
+		return zlib.NewReader(buffered)
+	default:
+		return io.NopCloser(buffered), nil
+	}
+}
+
+// DetectCompression determines the compression of data with the given name, starting with the given header bytes.
+// extensions take precedence over magic bytes, so a truncated or corrupted file fails to decompress rather than being read as text.
+func DetectCompression(name string, header []byte) Compression {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".gz":
+		return CompressionGzip
+	case ".bz2":
+		return CompressionBzip2
+	case ".zlib", ".zz":
+		return CompressionZlib
+	}
+
+	switch {
+	case bytes.HasPrefix(header, gzipMagic):
+		return CompressionGzip
+	case isBzip2Header(header):
+		return CompressionBzip2
+	default:
+		return CompressionNone
+	}
+}
+
+// utility to check for a bzip2 stream header, a "BZh" signature, a block size digit and the magic of either a block or the end of the stream.
+func isBzip2Header(header []byte) bool {
+	if len(header) < 10 || !bytes.HasPrefix(header, bzip2Magic) || header[3] < '1' || header[3] > '9' {
+		return false
+	}
+	return bytes.Equal(header[4:10], bzip2BlockMagic) || bytes.Equal(header[4:10], bzip2EndOfStream)
+}
+
+// filesReader reads a list of files one after the other, decompressing each as needed.
+type filesReader struct {
+	files    []string
+	current  io.ReadCloser
+	lastByte byte
+	readAny  bool // whether anything was read from the current file
+	pending  bool // whether a line break still has to be inserted before the next file
+}
+
+// Read reads from the current file, moving on to the next one once it is exhausted.
+func (r *filesReader) Read(p []byte) (int, error) {
+	for {
+		if r.pending {
+			if len(p) == 0 {
+				return 0, nil
+			}
+			p[0] = '\n'
+			r.pending = false
+			return 1, nil
+		}
+		if r.current == nil {
+			if len(r.files) == 0 {
+				return 0, io.EOF
+			}
+			current, err := OpenFile(r.files[0])
+			if err != nil {
+				return 0, err
+			}
+			r.current = current
+			r.files = r.files[1:]
+		}
+
+		n, err := r.current.Read(p)
+		if n > 0 {
+			r.lastByte = p[n-1]
+			r.readAny = true
+			return n, nil
+		}
+		if err == io.EOF {
+			if closeErr := r.closeCurrent(); closeErr != nil {
+				return 0, closeErr
+			}
+			r.pending = r.readAny && r.lastByte != '\n' && len(r.files) > 0
+			r.readAny = false
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+}
+
+// Close closes the file currently open, if any.
+func (r *filesReader) Close() error {
+	r.files = nil
+	return r.closeCurrent()
+}
+
+// utility to close the file currently open.
+func (r *filesReader) closeCurrent() error {
+	if r.current == nil {
+		return nil
+	}
+	err := r.current.Close()
+	r.current = nil
+	return err
+}
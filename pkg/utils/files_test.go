@@ -0,0 +1,80 @@
+package utils
+
+import (
+	"bytes"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// utility to write the given content to a file in dir, compressing it with the given writer constructor when not nil.
+func writeFile(t *testing.T, dir, name, content string, compress func(io.Writer) io.WriteCloser) string {
+	path := filepath.Join(dir, name)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+
+	var buf bytes.Buffer
+	if compress == nil {
+		buf.WriteString(content)
+	} else {
+		w := compress(&buf)
+		_, err := w.Write([]byte(content))
+		assert.NoError(t, err)
+		assert.NoError(t, w.Close())
+	}
+	assert.NoError(t, os.WriteFile(path, buf.Bytes(), 0o644))
+	return path
+}
+
+func gzipWriter(w io.Writer) io.WriteCloser { return gzip.NewWriter(w) }
+func zlibWriter(w io.Writer) io.WriteCloser { return zlib.NewWriter(w) }
+
+// utility to read everything from the files named by the given path.
+func readFiles(t *testing.T, path string) string {
+	r, err := OpenFiles(path)
+	assert.NoError(t, err)
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	assert.NoError(t, err)
+	return string(content)
+}
+
+func TestOpenFiles_Compressed(t *testing.T) {
+	dir := t.TempDir()
+
+	assert.Equal(t, "plain\n", readFiles(t, writeFile(t, dir, "plain.txt", "plain\n", nil)))
+	assert.Equal(t, "gzip\n", readFiles(t, writeFile(t, dir, "words.gz", "gzip\n", gzipWriter)))
+	assert.Equal(t, "zlib\n", readFiles(t, writeFile(t, dir, "words.zz", "zlib\n", zlibWriter)))
+	assert.Equal(t, "axpaj\ndnrbt\n", readFiles(t, "../../test_data/compressed/words.txt.bz2"))
+	assert.Equal(t, "detected\n", readFiles(t, writeFile(t, dir, "rotated.log.1", "detected\n", gzipWriter)), "gzip is detected by its magic bytes")
+}
+
+func TestOpenFiles_DirectoriesAndGlobs(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "logs/app.log", "first", nil)
+	writeFile(t, dir, "logs/app.log.1.gz", "second\n", gzipWriter)
+	writeFile(t, dir, "logs/nested/app.log.2", "", nil)
+	writeFile(t, dir, "logs/nested/app.log.3", "third\n", nil)
+	writeFile(t, dir, "other.txt", "other\n", nil)
+
+	assert.Equal(t, "first\nsecond\nthird\n", readFiles(t, filepath.Join(dir, "logs")), "A line break is inserted after a file not ending with one")
+	assert.Equal(t, "first\nsecond\n", readFiles(t, filepath.Join(dir, "logs", "app.log*")))
+
+	_, err := OpenFiles(filepath.Join(dir, "missing*"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+	_, err = OpenFiles(filepath.Join(dir, "missing.txt"))
+	assert.ErrorIs(t, err, fs.ErrNotExist)
+}
+
+func TestDetectCompression(t *testing.T) {
+	assert.Equal(t, CompressionGzip, DetectCompression("words", []byte{0x1f, 0x8b, 0x08}))
+	assert.Equal(t, CompressionBzip2, DetectCompression("words", []byte{'B', 'Z', 'h', '9', 0x31, 0x41, 0x59, 0x26, 0x53, 0x59}))
+	assert.Equal(t, CompressionNone, DetectCompression("words", []byte("BZh9 is not bzip2")))
+	assert.Equal(t, CompressionNone, DetectCompression("words", []byte{0x78, 0x9c}), "zlib is only detected by extension")
+	assert.Equal(t, CompressionZlib, DetectCompression("words.zlib", nil))
+}
@@ -24,5 +24,5 @@ func init() {
 
 	// TODO: Make log level configurable as well
 	Log.SetLevel(logrus.WarnLevel) // Default log level
-	Log.SetOutput(os.Stdout)
+	Log.SetOutput(os.Stderr)       // stdout is reserved for results, which may be machine-readable
 }
@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"context"
+	"runtime"
+	"sync"
+)
+
+// WorkerPool runs submitted tasks on a fixed number of long-lived goroutines, so it can be shared across many calls.
+// every task is told which worker runs it, tasks run by the same worker never run concurrently.
+type WorkerPool struct {
+	size      int
+	tasks     chan func(worker int)
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// creates a new WorkerPool with the given number of workers, defaulting to GOMAXPROCS when it is not positive.
+func NewWorkerPool(size int) *WorkerPool {
+	if size <= 0 {
+		size = runtime.GOMAXPROCS(0)
+	}
+
+	p := &WorkerPool{
+		size:  size,
+		tasks: make(chan func(worker int)),
+	}
+	for worker := 0; worker < size; worker++ {
+		p.wg.Add(1)
+		go p.work(worker)
+	}
+
+	Log.WithField("workers", size).Debug("Started worker pool")
+
+	return p
+}
+
+// Size returns the number of workers in the pool.
+func (p *WorkerPool) Size() int {
+	return p.size
+}
+
+// Submit blocks until a worker picks up the given task, or until the given context is done in which case the task never runs.
+func (p *WorkerPool) Submit(ctx context.Context, task func(worker int)) error {
+	select {
+	case p.tasks <- task:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Close stops the workers once they have finished their current tasks, no tasks may be submitted afterwards.
+func (p *WorkerPool) Close() {
+	p.closeOnce.Do(func() {
+		close(p.tasks)
+	})
+	p.wg.Wait()
+}
+
+// runs tasks on behalf of the given worker until the pool is closed.
+func (p *WorkerPool) work(worker int) {
+	defer p.wg.Done()
+	for task := range p.tasks {
+		task(worker)
+	}
+}
@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorkerPool_RunsEveryTask(t *testing.T) {
+	pool := NewWorkerPool(3)
+	defer pool.Close()
+
+	var count int64
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		err := pool.Submit(context.Background(), func(worker int) {
+			defer wg.Done()
+			assert.GreaterOrEqual(t, worker, 0)
+			assert.Less(t, worker, pool.Size())
+			atomic.AddInt64(&count, 1)
+		})
+		assert.NoError(t, err)
+	}
+	wg.Wait()
+
+	assert.Equal(t, int64(100), count)
+}
+
+func TestWorkerPool_DefaultSize(t *testing.T) {
+	pool := NewWorkerPool(0)
+	defer pool.Close()
+
+	assert.Positive(t, pool.Size(), "A non-positive size should default to GOMAXPROCS")
+}
+
+func TestWorkerPool_SubmitCanceled(t *testing.T) {
+	pool := NewWorkerPool(1)
+	defer pool.Close()
+
+	// keep the only worker busy, so that the next task cannot be picked up.
+	release := make(chan struct{})
+	assert.NoError(t, pool.Submit(context.Background(), func(int) { <-release }))
+	defer close(release)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err := pool.Submit(ctx, func(int) { t.Error("Task should not run once its context is done") })
+
+	assert.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,44 @@
+package utils
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// initialScanBufferSize is the size of the buffer a scanner starts with, it grows up to the maximum token size as needed.
+const initialScanBufferSize = 64 * 1024
+
+// NewScanner creates a line scanner over the given reader accepting lines of up to maxTokenSize bytes, bufio's default of 64KB when not positive.
+func NewScanner(r io.Reader, maxTokenSize int) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	if maxTokenSize > 0 {
+		scanner.Buffer(make([]byte, 0, minInt(initialScanBufferSize, maxTokenSize)), maxTokenSize)
+	}
+	return scanner
+}
+
+// ScanError returns the error the given scanner stopped with, nil once it reached the end of its reader.
+// a line over the maximum token size is reported along with its line number and the limit, wrapping bufio.ErrTooLong.
+func ScanError(scanner *bufio.Scanner, line, maxTokenSize int) error {
+	err := scanner.Err()
+	if errors.Is(err, bufio.ErrTooLong) {
+		if maxTokenSize <= 0 {
+			maxTokenSize = bufio.MaxScanTokenSize
+		}
+		return fmt.Errorf("line %d is longer than the maximum token size of %d bytes, raise MAX_TOKEN_SIZE to read it: %w", line, maxTokenSize, err)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read line %d: %w", line, err)
+	}
+	return nil
+}
+
+// utility to get the smaller of two integers.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
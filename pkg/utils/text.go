@@ -0,0 +1,258 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"golang.org/x/text/cases"
+	"golang.org/x/text/unicode/norm"
+)
+
+// Grapheme is a user-perceived character of a string, along with the byte offset it starts at.
+type Grapheme struct {
+	Offset int
+	Text   string
+}
+
+// End returns the byte offset right after the grapheme.
+func (g Grapheme) End() int {
+	return g.Offset + len(g.Text)
+}
+
+const (
+	zeroWidthJoiner    = '\u200d'
+	regionalIndicatorA = '\U0001f1e6'
+	regionalIndicatorZ = '\U0001f1ff'
+)
+
+// IsPunctuationOrSpace reports whether the grapheme is a punctuation mark or whitespace, rather than a letter, digit or symbol.
+func (g Grapheme) IsPunctuationOrSpace() bool {
+	r, _ := utf8.DecodeRuneInString(g.Text)
+	return unicode.IsPunct(r) || unicode.IsSpace(r)
+}
+
+// WithoutPunctuation returns the graphemes that are neither punctuation nor whitespace, keeping their original offsets.
+func WithoutPunctuation(graphemes []Grapheme) []Grapheme {
+	significant := make([]Grapheme, 0, len(graphemes))
+	for _, g := range graphemes {
+		if !g.IsPunctuationOrSpace() {
+			significant = append(significant, g)
+		}
+	}
+	return significant
+}
+
+// Graphemes splits the given string into user-perceived characters.
+// this approximates extended grapheme clusters: a base rune is kept together with any following combining marks,
+// variation selectors and emoji modifiers, runes joined by a zero width joiner, and pairs of regional indicators (flags).
+func Graphemes(s string) []Grapheme {
+	graphemes := make([]Grapheme, 0, len(s))
+	start := 0
+	var previous rune
+	regionalIndicators := 0
+	for offset, r := range s {
+		if offset > start && !extendsGrapheme(previous, r, regionalIndicators) {
+			graphemes = append(graphemes, Grapheme{Offset: start, Text: s[start:offset]})
+			start = offset
+			regionalIndicators = 0
+		}
+		if isRegionalIndicator(r) {
+			regionalIndicators++
+		}
+		previous = r
+	}
+	if start < len(s) {
+		graphemes = append(graphemes, Grapheme{Offset: start, Text: s[start:]})
+	}
+	return graphemes
+}
+
+// GraphemeCount returns the number of user-perceived characters in the given string, as split by Graphemes.
+func GraphemeCount(s string) int {
+	count := 0
+	var previous rune
+	regionalIndicators := 0
+	for offset, r := range s {
+		if offset == 0 || !extendsGrapheme(previous, r, regionalIndicators) {
+			count++
+			regionalIndicators = 0
+		}
+		if isRegionalIndicator(r) {
+			regionalIndicators++
+		}
+		previous = r
+	}
+	return count
+}
+
+// utility to decide whether the given rune continues the grapheme that the previous rune belongs to.
+func extendsGrapheme(previous, r rune, regionalIndicators int) bool {
+	switch {
+	case unicode.In(r, unicode.Mn, unicode.Me, unicode.Mc):
+		return true
+	case r == zeroWidthJoiner || previous == zeroWidthJoiner:
+		return true
+	case unicode.Is(unicode.Variation_Selector, r):
+		return true
+	case r >= '\U0001f3fb' && r <= '\U0001f3ff': // emoji skin tone modifiers
+		return true
+	case isRegionalIndicator(r) && isRegionalIndicator(previous):
+		return regionalIndicators%2 == 1
+	default:
+		return false
+	}
+}
+
+// utility to check whether the given rune is a regional indicator, two of which form a flag.
+func isRegionalIndicator(r rune) bool {
+	return r >= regionalIndicatorA && r <= regionalIndicatorZ
+}
+
+// Normalization names a Unicode normalization form.
+type Normalization string
+
+const (
+	// NormalizationNone leaves text as it is.
+	NormalizationNone Normalization = "none"
+	// NormalizationNFC composes characters, e.g. "e" followed by a combining acute accent becomes "é".
+	NormalizationNFC Normalization = "nfc"
+	// NormalizationNFD decomposes characters, e.g. "é" becomes "e" followed by a combining acute accent.
+	NormalizationNFD Normalization = "nfd"
+	// NormalizationNFKC composes characters after replacing compatibility characters, e.g. "ﬁ" becomes "fi".
+	NormalizationNFKC Normalization = "nfkc"
+	// NormalizationNFKD decomposes characters after replacing compatibility characters.
+	NormalizationNFKD Normalization = "nfkd"
+)
+
+// ParseNormalization converts the given name into a Normalization, an empty name selects NormalizationNone.
+func ParseNormalization(name string) (Normalization, error) {
+	switch Normalization(strings.ToLower(name)) {
+	case "", NormalizationNone:
+		return NormalizationNone, nil
+	case NormalizationNFC, NormalizationNFD, NormalizationNFKC, NormalizationNFKD:
+		return Normalization(strings.ToLower(name)), nil
+	default:
+		return "", fmt.Errorf("unknown normalization %q, expected one of: %s, %s, %s, %s, %s",
+			name, NormalizationNone, NormalizationNFC, NormalizationNFD, NormalizationNFKC, NormalizationNFKD)
+	}
+}
+
+// Normalizer rewrites text so that canonically or compatibly equivalent strings compare equal, optionally folding accents and case away.
+type Normalizer struct {
+	form        Normalization
+	foldAccents bool
+	foldCase    bool
+}
+
+// creates a new Normalizer with the given configuration, unknown normalization forms leave text as it is.
+func NewNormalizer(cfg config.TextConfig) *Normalizer {
+	form, err := ParseNormalization(cfg.Normalization)
+	if err != nil {
+		Log.WithError(err).Warn("Falling back to no normalization")
+		form = NormalizationNone
+	}
+	return &Normalizer{form: form, foldAccents: cfg.FoldAccents, foldCase: cfg.FoldCase}
+}
+
+// Normalize returns the given string in the configured normalization form, with case and accents folded away if configured.
+// case folding is full Unicode case folding, e.g. "Straße" becomes "strasse".
+// accent folding decomposes the string, drops every non-spacing mark and recomposes it, unless a decomposed form is configured.
+func (n *Normalizer) Normalize(s string) string {
+	if n.foldCase {
+		// a Caser holds state and is not safe for concurrent use, so every call gets its own.
+		s = cases.Fold().String(s)
+	}
+	if n.foldAccents {
+		s = foldAccents(s)
+		if n.form == NormalizationNone {
+			return norm.NFC.String(s)
+		}
+	}
+
+	switch n.form {
+	case NormalizationNFC:
+		return norm.NFC.String(s)
+	case NormalizationNFD:
+		return norm.NFD.String(s)
+	case NormalizationNFKC:
+		return norm.NFKC.String(s)
+	case NormalizationNFKD:
+		return norm.NFKD.String(s)
+	default:
+		return s
+	}
+}
+
+// NormalizeWithOffsets returns the given string normalized like Normalize, along with a map from byte offsets in the result back to the given string.
+// the string is normalized one grapheme at a time, so that every byte of the result comes from a single grapheme of the given string.
+// this only differs from normalizing it as a whole where normalization would merge separate graphemes, which real text hardly ever relies on.
+func (n *Normalizer) NormalizeWithOffsets(s string) (string, OffsetMap) {
+	if n.IsIdentity() {
+		return s, OffsetMap{}
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	starts := make([]int, 0, len(s)+1)
+	for _, g := range Graphemes(s) {
+		before := b.Len()
+		if c := g.Text[0]; len(g.Text) == 1 && c < utf8.RuneSelf {
+			// ASCII is left as it is by every normalization form and by accent folding, only case folding changes it.
+			if n.foldCase && 'A' <= c && c <= 'Z' {
+				c += 'a' - 'A'
+			}
+			b.WriteByte(c)
+		} else {
+			b.WriteString(n.Normalize(g.Text))
+		}
+		for i := before; i < b.Len(); i++ {
+			starts = append(starts, g.Offset)
+		}
+	}
+	starts = append(starts, len(s))
+	return b.String(), OffsetMap{starts: starts}
+}
+
+// OffsetMap maps byte offsets in a normalized string back to the string it was normalized from, as returned by NormalizeWithOffsets.
+// the zero OffsetMap is the identity, for normalizers that leave strings as they are.
+type OffsetMap struct {
+	starts []int // offset of the grapheme of the original string every byte of the normalized string comes from, followed by its length
+}
+
+// Span returns the byte range of the original string the given byte range of the normalized string comes from.
+// a range covering only part of what a grapheme was normalized into, e.g. "f" out of "ﬁ" with NFKC, is widened to the whole grapheme.
+func (m OffsetMap) Span(start, end int) (int, int) {
+	if m.starts == nil {
+		return start, end
+	}
+	if end <= start {
+		return m.starts[start], m.starts[start]
+	}
+	last := m.starts[end-1]
+	for end < len(m.starts)-1 && m.starts[end] == last {
+		end++
+	}
+	return m.starts[start], m.starts[end]
+}
+
+// IsIdentity reports whether Normalize leaves every string as it is.
+func (n *Normalizer) IsIdentity() bool {
+	return n.form == NormalizationNone && !n.foldAccents && !n.foldCase
+}
+
+// utility to strip accents, by decomposing the string and dropping its non-spacing marks.
+func foldAccents(s string) string {
+	decomposed := norm.NFD.String(s)
+	var b strings.Builder
+	b.Grow(len(decomposed))
+	for _, r := range decomposed {
+		if unicode.Is(unicode.Mn, r) {
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGraphemes checks that user-perceived characters are kept together, along with their byte offsets.
+func TestGraphemes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "", expected: []string{}},
+		{input: "abc", expected: []string{"a", "b", "c"}},
+		{input: "cafe\u0301", expected: []string{"c", "a", "f", "e\u0301"}},
+		{input: "नमस्ते", expected: []string{"न", "म", "स्", "ते"}},
+		{input: "👍🏽x", expected: []string{"👍🏽", "x"}},
+		{input: "👨‍👩‍👧", expected: []string{"👨‍👩‍👧"}},
+		{input: "🇳🇿🇦🇺", expected: []string{"🇳🇿", "🇦🇺"}},
+		{input: "❤️a", expected: []string{"❤️", "a"}},
+	}
+
+	for _, tt := range tests {
+		graphemes := Graphemes(tt.input)
+		texts := make([]string, len(graphemes))
+		for i, g := range graphemes {
+			texts[i] = g.Text
+			assert.Equal(t, g.Text, tt.input[g.Offset:g.End()], "input=%q", tt.input)
+		}
+		assert.Equal(t, tt.expected, texts, "input=%q", tt.input)
+		assert.Equal(t, len(tt.expected), GraphemeCount(tt.input), "input=%q", tt.input)
+	}
+}
+
+// TestNormalizer checks every normalization form along with accent folding.
+func TestNormalizer(t *testing.T) {
+	composed := "caf\u00e9"
+	decomposed := "cafe\u0301"
+
+	tests := []struct {
+		cfg      config.TextConfig
+		input    string
+		expected string
+	}{
+		{cfg: config.TextConfig{}, input: decomposed, expected: decomposed},
+		{cfg: config.TextConfig{Normalization: "nfc"}, input: decomposed, expected: composed},
+		{cfg: config.TextConfig{Normalization: "NFD"}, input: composed, expected: decomposed},
+		{cfg: config.TextConfig{Normalization: "nfkc"}, input: "\ufb01le", expected: "file"},
+		{cfg: config.TextConfig{Normalization: "nfkd"}, input: "\ufb01l\u00e9", expected: "file\u0301"},
+		{cfg: config.TextConfig{FoldAccents: true}, input: "Crème brûlée", expected: "Creme brulee"},
+		{cfg: config.TextConfig{FoldAccents: true}, input: "Ελληνικά", expected: "Ελληνικα"},
+		{cfg: config.TextConfig{Normalization: "unknown"}, input: decomposed, expected: decomposed},
+		{cfg: config.TextConfig{FoldCase: true}, input: "Straße ΣΊΣΥΦΟΣ", expected: "strasse σίσυφοσ"},
+		{cfg: config.TextConfig{FoldCase: true, FoldAccents: true}, input: "CAFÉ", expected: "cafe"},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, NewNormalizer(tt.cfg).Normalize(tt.input), "cfg=%+v input=%q", tt.cfg, tt.input)
+		normalized, _ := NewNormalizer(tt.cfg).NormalizeWithOffsets(tt.input)
+		assert.Equal(t, tt.expected, normalized, "cfg=%+v input=%q", tt.cfg, tt.input)
+	}
+	assert.True(t, NewNormalizer(config.TextConfig{}).IsIdentity())
+	assert.False(t, NewNormalizer(config.TextConfig{FoldAccents: true}).IsIdentity())
+	assert.False(t, NewNormalizer(config.TextConfig{FoldCase: true}).IsIdentity())
+}
+
+// TestNormalizer_Offsets checks that ranges of a normalized string map back to the graphemes of the original string they come from.
+func TestNormalizer_Offsets(t *testing.T) {
+	normalizer := NewNormalizer(config.TextConfig{Normalization: "nfkd", FoldAccents: true, FoldCase: true})
+	input := "Straße \ufb01CAFÉ"
+	normalized, offsets := normalizer.NormalizeWithOffsets(input)
+	assert.Equal(t, "strasse ficafe", normalized)
+
+	span := func(start, end int) string {
+		start, end = offsets.Span(start, end)
+		return input[start:end]
+	}
+	assert.Equal(t, "Straße", span(0, 7))
+	assert.Equal(t, "ß", span(4, 5), "Part of what a grapheme became maps to the whole grapheme")
+	assert.Equal(t, "\ufb01", span(8, 9))
+	assert.Equal(t, "\ufb01CAFÉ", span(8, 14))
+	assert.Equal(t, "É", span(13, 14))
+
+	identity, offsets := NewNormalizer(config.TextConfig{}).NormalizeWithOffsets(input)
+	assert.Equal(t, input, identity)
+	start, end := offsets.Span(1, 4)
+	assert.Equal(t, []int{1, 4}, []int{start, end})
+}
+
+// TestWithoutPunctuation checks that punctuation and whitespace are dropped while offsets into the original string are kept.
+func TestWithoutPunctuation(t *testing.T) {
+	input := "it's a-ok 👍🏽!"
+	var texts []string
+	for _, g := range WithoutPunctuation(Graphemes(input)) {
+		texts = append(texts, g.Text)
+		assert.Equal(t, g.Text, input[g.Offset:g.End()])
+	}
+	assert.Equal(t, []string{"i", "t", "s", "a", "o", "k", "👍🏽"}, texts)
+}
@@ -1,14 +1,44 @@
 package utils
 
+import "sort"
+
 // Node represents a node in the Trie.
+// the node a word ends at carries the source words it was inserted for, e.g. the dictionary words sharing a scrambled key.
 type Node struct {
 	Children map[rune]*Node
 	IsWord   bool
+	Words    []string
 }
 
 // Trie represents the Trie data structure.
 type Trie struct {
 	Root *Node
+	size int
+}
+
+// TrieStats describes the shape of a trie, as returned by Stats.
+type TrieStats struct {
+	Words        int     // number of words stored
+	Nodes        int     // number of nodes, including the root
+	Leaves       int     // number of nodes without children
+	MaxDepth     int     // length in runes of the longest word
+	AverageDepth float64 // average length in runes of the words, 0 if there are none
+}
+
+// utility to account for a node at the given depth in the statistics, word whether a word ends at it and leaf whether it has no children.
+func (s *TrieStats) add(depth int, word, leaf bool) {
+	s.Nodes++
+	if leaf {
+		s.Leaves++
+	}
+	if !word {
+		return
+	}
+	s.AverageDepth = (s.AverageDepth*float64(s.Words) + float64(depth)) / float64(s.Words+1)
+	s.Words++
+	if depth > s.MaxDepth {
+		s.MaxDepth = depth
+	}
 }
 
 // NewTrie creates a new Trie instance.
@@ -18,6 +48,23 @@ func NewTrie() *Trie {
 
 // Insert inserts a word into the Trie.
 func (t *Trie) Insert(word string) {
+	t.insert(word)
+}
+
+// InsertWithSource inserts a word into the Trie, recording the given source word on its node.
+// a source word is recorded once, however often it is inserted.
+func (t *Trie) InsertWithSource(word, source string) {
+	node := t.insert(word)
+	for _, existing := range node.Words {
+		if existing == source {
+			return
+		}
+	}
+	node.Words = append(node.Words, source)
+}
+
+// utility to insert a word into the Trie, returning the node it ends at.
+func (t *Trie) insert(word string) *Node {
 	node := t.Root
 	for _, r := range word {
 		if child, ok := node.Children[r]; ok {
@@ -28,18 +75,128 @@ func (t *Trie) Insert(word string) {
 			node = child
 		}
 	}
-	node.IsWord = true
+	if !node.IsWord {
+		node.IsWord = true
+		t.size++
+	}
+	return node
+}
+
+// Delete removes a word and its source words from the Trie, returning whether it was there.
+// nodes left without a word below them are removed as well, so an automaton built with BuildAutomaton must be built again.
+func (t *Trie) Delete(word string) bool {
+	path := []*Node{t.Root}
+	runes := []rune(word)
+	for _, r := range runes {
+		child, ok := path[len(path)-1].Children[r]
+		if !ok {
+			return false
+		}
+		path = append(path, child)
+	}
+	node := path[len(path)-1]
+	if !node.IsWord {
+		return false
+	}
+	node.IsWord = false
+	node.Words = nil
+	t.size--
+
+	for i := len(path) - 1; i > 0 && !path[i].IsWord && len(path[i].Children) == 0; i-- {
+		delete(path[i-1].Children, runes[i-1])
+	}
+	return true
 }
 
 // Find checks if a word is in the Trie.
 func (t *Trie) Find(word string) bool {
+	node := t.node(word)
+	return node != nil && node.IsWord
+}
+
+// Lookup returns the source words recorded for a word, in insertion order, or nil if the word is not in the Trie.
+func (t *Trie) Lookup(word string) []string {
+	node := t.node(word)
+	if node == nil || !node.IsWord {
+		return nil
+	}
+	return node.Words
+}
+
+// utility to walk the Trie along a word, returning the node it ends at or nil if there is none.
+func (t *Trie) node(word string) *Node {
 	node := t.Root
 	for _, r := range word {
-		if child, ok := node.Children[r]; ok {
-			node = child
-		} else {
+		child, ok := node.Children[r]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
+// HasPrefix checks if any word in the Trie starts with the given prefix, every word starts with the empty prefix.
+func (t *Trie) HasPrefix(prefix string) bool {
+	if prefix == "" {
+		return t.size > 0
+	}
+	return t.node(prefix) != nil
+}
+
+// WordsWithPrefix returns the words in the Trie starting with the given prefix, ordered by their runes.
+func (t *Trie) WordsWithPrefix(prefix string) []string {
+	node := t.node(prefix)
+	if node == nil {
+		return nil
+	}
+	var words []string
+	t.walk(node, []rune(prefix), func(word string, _ []string) bool {
+		words = append(words, word)
+		return true
+	})
+	return words
+}
+
+// Walk calls fn with every word in the Trie and its source words, ordered by their runes, until fn returns false.
+// the Trie must not be changed during the walk.
+func (t *Trie) Walk(fn func(word string, sources []string) bool) {
+	t.walk(t.Root, nil, fn)
+}
+
+// utility to walk the words below a node, the given prefix being the runes leading to it, returning false once fn did.
+func (t *Trie) walk(node *Node, prefix []rune, fn func(word string, sources []string) bool) bool {
+	if node.IsWord && !fn(string(prefix), node.Words) {
+		return false
+	}
+	runes := make([]rune, 0, len(node.Children))
+	for r := range node.Children {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	for _, r := range runes {
+		if !t.walk(node.Children[r], append(prefix, r), fn) {
 			return false
 		}
 	}
-	return node.IsWord
+	return true
+}
+
+// Len returns the number of words in the Trie.
+func (t *Trie) Len() int {
+	return t.size
+}
+
+// Stats returns the number of words and nodes of the Trie, and the depth of its words.
+func (t *Trie) Stats() TrieStats {
+	var stats TrieStats
+	var visit func(node *Node, depth int)
+	visit = func(node *Node, depth int) {
+		stats.add(depth, node.IsWord, len(node.Children) == 0)
+		for _, child := range node.Children {
+			visit(child, depth+1)
+		}
+	}
+	visit(t.Root, 0)
+	return stats
 }
@@ -0,0 +1,68 @@
+package utils
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTrie_LookupReturnsSourceWords checks that every source word inserted under a word is returned once, in insertion order.
+func TestTrie_LookupReturnsSourceWords(t *testing.T) {
+	trie := NewTrie()
+	trie.InsertWithSource("abt", "tab")
+	trie.InsertWithSource("abt", "bat")
+	trie.InsertWithSource("abt", "tab")
+	trie.InsertWithSource("act", "cat")
+	trie.Insert("ab")
+
+	assert.Equal(t, []string{"tab", "bat"}, trie.Lookup("abt"))
+	assert.Equal(t, []string{"cat"}, trie.Lookup("act"))
+	assert.Empty(t, trie.Lookup("ab"), "Words inserted without a source carry none")
+	assert.True(t, trie.Find("ab"))
+	assert.Nil(t, trie.Lookup("a"), "Prefixes are not words")
+	assert.Nil(t, trie.Lookup("xyz"))
+}
+
+// TestWordTrie_DeletePrefixesAndWalk checks deleting words, prefix queries, walks and statistics, on both implementations.
+func TestWordTrie_DeletePrefixesAndWalk(t *testing.T) {
+	for _, trie := range []WordTrie{NewTrie(), NewCompactTrie()} {
+		name := fmt.Sprintf("%T", trie)
+		assert.False(t, trie.HasPrefix(""), name)
+		for _, word := range []string{"tea", "ten", "to", "inn", "in", "tea"} {
+			trie.InsertWithSource(word, word+"!")
+		}
+
+		assert.Equal(t, 5, trie.Len(), name)
+		assert.Equal(t, TrieStats{Words: 5, Nodes: 9, Leaves: 4, MaxDepth: 3, AverageDepth: 2.6}, trie.Stats(), name)
+		assert.True(t, trie.HasPrefix(""), name)
+		assert.True(t, trie.HasPrefix("te"), name)
+		assert.False(t, trie.HasPrefix("tx"), name)
+		assert.Equal(t, []string{"tea", "ten"}, trie.WordsWithPrefix("te"), name)
+		assert.Equal(t, []string{"in", "inn"}, trie.WordsWithPrefix("in"), name)
+		assert.Nil(t, trie.WordsWithPrefix("x"), name)
+
+		var walked []string
+		trie.Walk(func(word string, sources []string) bool {
+			assert.Equal(t, []string{word + "!"}, sources, name)
+			walked = append(walked, word)
+			return len(walked) < 4
+		})
+		assert.Equal(t, []string{"in", "inn", "tea", "ten"}, walked, "%s: words are walked in order, until fn returns false", name)
+
+		assert.True(t, trie.Delete("tea"), name)
+		assert.False(t, trie.Delete("tea"), name)
+		assert.False(t, trie.Delete("t"), "%s: prefixes are not words", name)
+		assert.True(t, trie.Delete("in"), name)
+		assert.False(t, trie.Find("tea"), name)
+		assert.Nil(t, trie.Lookup("in"), name)
+		assert.True(t, trie.Find("inn"), "%s: deleting a word keeps the words below it", name)
+		assert.Equal(t, 3, trie.Len(), name)
+		assert.Equal(t, TrieStats{Words: 3, Nodes: 8, Leaves: 3, MaxDepth: 3, AverageDepth: 8.0 / 3}, trie.Stats(), "%s: empty nodes are removed", name)
+		assert.False(t, trie.HasPrefix("tea"), name)
+
+		trie.InsertWithSource("tea", "eat")
+		assert.Equal(t, []string{"eat"}, trie.Lookup("tea"), "%s: source words of deleted words are gone", name)
+		assert.Equal(t, 9, trie.Stats().Nodes, name)
+	}
+}
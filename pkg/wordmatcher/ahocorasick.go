@@ -0,0 +1,51 @@
+package wordmatcher
+
+import (
+	"context"
+	"sort"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// finds all exact occurrences of the words of the given automaton in the given input string, split into the given graphemes, in a single pass.
+// occurrences are only reported when they start and end on grapheme boundaries, so a word never matches part of a character.
+// the context is checked every contextCheckInterval graphemes, returning the hits found so far once it is done.
+func findExactHits(ctx context.Context, input string, graphemes []utils.Grapheme, automaton *utils.Automaton) ([]hit, error) {
+	// runeEnds[i] is the number of runes read once the first i graphemes are read, so a match of d runes ending after
+	// grapheme i starts on a grapheme boundary exactly when runeEnds[i+1]-d is itself one of runeEnds.
+	runeEnds := make([]int, len(graphemes)+1)
+
+	hits := make(map[hit]struct{})
+	state := automaton.Root
+	for i, g := range graphemes {
+		if i%contextCheckInterval == 0 && ctx.Err() != nil {
+			return sortedHits(hits), ctx.Err()
+		}
+
+		runeEnds[i+1] = runeEnds[i]
+		for _, r := range g.Text {
+			state = automaton.Next(state, r)
+			runeEnds[i+1]++
+		}
+
+		for output := state; output != nil; output = output.Output {
+			if !output.Node.IsWord {
+				continue
+			}
+			startRunes := runeEnds[i+1] - output.Depth
+			start := sort.SearchInts(runeEnds[:i+1], startRunes)
+			if start > i || runeEnds[start] != startRunes {
+				continue
+			}
+			text := input[graphemes[start].Offset:g.End()]
+			hits[hit{offset: graphemes[start].Offset, text: text, key: joinGraphemes(graphemes[start : i+1])}] = struct{}{}
+		}
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"inputLength": len(graphemes),
+		"hitCount":    len(hits),
+	}).Debug("Scanned input with Aho–Corasick automaton")
+
+	return sortedHits(hits), nil
+}
@@ -0,0 +1,83 @@
+package wordmatcher
+
+import (
+	"context"
+	"sort"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// LineMatcher finds the occurrences of dictionary words in input lines, it is implemented by Matcher and CombinedMatcher.
+type LineMatcher interface {
+	MatchLine(ctx context.Context, line int, input string) ([]Match, error)
+	Close()
+}
+
+// CombinedMatcher runs several matchers over every line and merges their matches, e.g. an Aho–Corasick matcher finding
+// the words of an exact policy in a single pass alongside a matcher finding scrambled ones.
+type CombinedMatcher struct {
+	matchers []LineMatcher
+}
+
+// creates a new CombinedMatcher merging the matches of the given matchers.
+func NewCombinedMatcher(matchers ...LineMatcher) *CombinedMatcher {
+	return &CombinedMatcher{matchers: matchers}
+}
+
+// MatchLine finds every occurrence of every dictionary word in the given input line with every matcher, ordered by offset.
+// an occurrence found by several matchers is reported once, is exact if any of them found it to be, and keeps the smallest distance.
+// once the given context is done the remaining matchers are skipped, and the context's error is returned along with the merged matches
+// of the matchers that ran, including the partial matches of the matcher that was interrupted.
+func (c *CombinedMatcher) MatchLine(ctx context.Context, line int, input string) ([]Match, error) {
+	type occurrence struct {
+		dictionary string
+		word       string
+		offset     int
+		text       string
+	}
+
+	seen := make(map[occurrence]int)
+	var matches []Match
+	for _, matcher := range c.matchers {
+		found, err := matcher.MatchLine(ctx, line, input)
+		for _, match := range found {
+			o := occurrence{dictionary: match.Dictionary, word: match.Word, offset: match.Offset, text: match.Text}
+			if i, exists := seen[o]; exists {
+				matches[i].Exact = matches[i].Exact || match.Exact
+				matches[i].Distance = minOf(matches[i].Distance, match.Distance)
+				continue
+			}
+			seen[o] = len(matches)
+			matches = append(matches, match)
+		}
+		if err != nil {
+			return sortedMatches(matches), err
+		}
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"line":         line,
+		"matcherCount": len(c.matchers),
+		"matchCount":   len(matches),
+	}).Debug("Combined line matches")
+
+	return sortedMatches(matches), nil
+}
+
+// Close closes every matcher of the CombinedMatcher.
+func (c *CombinedMatcher) Close() {
+	for _, matcher := range c.matchers {
+		matcher.Close()
+	}
+}
+
+// utility to order matches by offset and then by length, keeping the order of matches of the same occurrence.
+func sortedMatches(matches []Match) []Match {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Offset != matches[j].Offset {
+			return matches[i].Offset < matches[j].Offset
+		}
+		return len(matches[i].Text) < len(matches[j].Text)
+	})
+	return matches
+}
@@ -0,0 +1,60 @@
+package wordmatcher
+
+import (
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// CompiledWord is a dictionary entry ready to be inserted into a Matcher: its word normalized, split into graphemes and reduced to its key.
+// Mode is the mode the key was computed in, the effective mode of the entry's policy, and the graphemes are substrings of Normalized.
+type CompiledWord struct {
+	Entry      dictionary.Entry
+	Mode       Mode
+	Normalized string
+	Graphemes  []utils.Grapheme
+	Key        string
+}
+
+// CompileEntries normalizes the words of the given entries and computes their keys, honouring the match policy of every entry.
+// the compiled words are in the order of the entries, and can be turned into a LineMatcher with NewMatcherFromCompiled.
+func CompileEntries(entries []dictionary.Entry, cfg config.AppConfig) []CompiledWord {
+	mode, err := ParseMode(cfg.Mode)
+	if err != nil {
+		mode = ModeAnagram
+	}
+
+	type compilerKey struct {
+		mode     Mode
+		foldCase bool
+	}
+	compilers := make(map[compilerKey]*Matcher)
+	words := make([]CompiledWord, len(entries))
+	for i, entry := range entries {
+		key := compilerKey{mode: policyMode(entry.Policy, mode), foldCase: cfg.FoldCase || entry.Policy.IgnoreCase}
+		compiler, exists := compilers[key]
+		if !exists {
+			compilerCfg := policyConfig(cfg, key.mode, key.foldCase)
+			compilerCfg.Engine = string(EngineTrie) // the engine plays no part in compiling words
+			compiler = newMatcher(compilerCfg, 0)
+			compilers[key] = compiler
+		}
+		words[i] = compiler.compile(entry)
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"wordCount":     len(words),
+		"compilerCount": len(compilers),
+	}).Debug("Compiled dictionary entries")
+
+	return words
+}
+
+// Entries returns the dictionary entries of the given compiled words, in order.
+func Entries(words []CompiledWord) []dictionary.Entry {
+	entries := make([]dictionary.Entry, len(words))
+	for i, w := range words {
+		entries[i] = w.Entry
+	}
+	return entries
+}
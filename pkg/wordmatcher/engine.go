@@ -0,0 +1,69 @@
+package wordmatcher
+
+import (
+	"fmt"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// Engine selects the algorithm used to find candidate substrings in an input line.
+type Engine string
+
+const (
+	// EngineTrie enumerates every substring of every chunk and looks its key up in the trie.
+	EngineTrie Engine = "trie"
+	// EngineWindow slides a fixed size letter-count window per dictionary word length across the whole line.
+	EngineWindow Engine = "window"
+	// EngineAhoCorasick runs an Aho–Corasick automaton over the whole line in a single pass, it only supports ModeExact.
+	EngineAhoCorasick Engine = "aho-corasick"
+	// EngineFuzzy walks the trie with a bounded number of edits from every position of the line, finding substrings near dictionary words.
+	EngineFuzzy Engine = "fuzzy"
+	// EngineGapped finds the letters of dictionary words spread across a window of at most MaxSpan characters, with filler characters between them.
+	EngineGapped Engine = "gapped"
+)
+
+// ParseEngine converts the given name into an Engine, an empty name selects EngineTrie.
+func ParseEngine(name string) (Engine, error) {
+	switch Engine(name) {
+	case "", EngineTrie:
+		return EngineTrie, nil
+	case EngineWindow:
+		return EngineWindow, nil
+	case EngineAhoCorasick:
+		return EngineAhoCorasick, nil
+	case EngineFuzzy:
+		return EngineFuzzy, nil
+	case EngineGapped:
+		return EngineGapped, nil
+	default:
+		return "", fmt.Errorf("unknown match engine %q, expected one of: %s, %s, %s, %s, %s", name, EngineTrie, EngineWindow, EngineAhoCorasick, EngineFuzzy, EngineGapped)
+	}
+}
+
+// CheckTrieImpl returns an error if the given engine cannot store its keys in the given trie implementation.
+// the Aho–Corasick and fuzzy engines walk the nodes of a utils.Trie, so they only support utils.TrieMap.
+func CheckTrieImpl(impl utils.TrieImpl, engine Engine) error {
+	if impl != utils.TrieMap && (engine == EngineAhoCorasick || engine == EngineFuzzy) {
+		return fmt.Errorf("match engine %s walks the nodes of a %s trie, it cannot be used with a %s trie", engine, utils.TrieMap, impl)
+	}
+	return nil
+}
+
+// CheckEngine returns an error if the given engine cannot be used with the given mode.
+func CheckEngine(engine Engine, mode Mode) error {
+	if engine == EngineAhoCorasick && mode != ModeExact {
+		return fmt.Errorf("match engine %s only finds exact occurrences, it cannot be used with match mode %s", engine, mode)
+	}
+	return nil
+}
+
+// CheckExactEngine returns an error if the given engine cannot find the words of an exact policy,
+// only the engines reporting exact occurrences of a word, without edits or filler characters, can.
+func CheckExactEngine(engine Engine) error {
+	switch engine {
+	case EngineTrie, EngineWindow, EngineAhoCorasick:
+		return nil
+	default:
+		return fmt.Errorf("match engine %s does not find exact occurrences, expected one of: %s, %s, %s", engine, EngineTrie, EngineWindow, EngineAhoCorasick)
+	}
+}
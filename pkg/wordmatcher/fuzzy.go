@@ -0,0 +1,365 @@
+package wordmatcher
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// Metric selects how the fuzzy engine measures the distance between an input substring and a dictionary word.
+type Metric string
+
+const (
+	// MetricLevenshtein counts the characters to insert, delete or substitute to turn the substring into the word, it only supports ModeExact.
+	MetricLevenshtein Metric = "levenshtein"
+	// MetricMultiset ignores the order of characters and counts the characters to add, remove or replace to turn the substring into a scramble of the word.
+	MetricMultiset Metric = "multiset"
+)
+
+// ParseMetric converts the given name into a Metric, an empty name selects the default metric of the given mode.
+func ParseMetric(name string, mode Mode) (Metric, error) {
+	switch Metric(name) {
+	case "":
+		return defaultMetric(mode), nil
+	case MetricLevenshtein, MetricMultiset:
+		return Metric(name), nil
+	default:
+		return "", fmt.Errorf("unknown distance metric %q, expected one of: %s, %s", name, MetricLevenshtein, MetricMultiset)
+	}
+}
+
+// CheckMetric returns an error if the given metric cannot be used with the given mode.
+func CheckMetric(metric Metric, mode Mode) error {
+	if metric == MetricLevenshtein && mode != ModeExact {
+		return fmt.Errorf("distance metric %s compares characters in order, it cannot be used with match mode %s", metric, mode)
+	}
+	return nil
+}
+
+// utility to determine the metric used when none is configured, levenshtein in exact mode and multiset in the scrambled modes.
+func defaultMetric(mode Mode) Metric {
+	if mode == ModeExact {
+		return MetricLevenshtein
+	}
+	return MetricMultiset
+}
+
+// fuzzyIndex holds the settings of the fuzzy engine, along with the range of candidate substring lengths they imply.
+type fuzzyIndex struct {
+	mode        Mode
+	metric      Metric
+	maxDistance int
+	minLength   int // length in characters of the shortest candidate substring
+	maxLength   int // length in characters of the longest candidate substring
+}
+
+// creates a new fuzzyIndex for the given dictionary words, each split into graphemes.
+// candidate substrings are up to maxDistance characters shorter or longer than the dictionary words.
+func newFuzzyIndex(words [][]utils.Grapheme, mode Mode, metric Metric, maxDistance int) *fuzzyIndex {
+	if maxDistance < 0 {
+		maxDistance = 0
+	}
+	f := &fuzzyIndex{mode: mode, metric: metric, maxDistance: maxDistance}
+	for _, graphemes := range words {
+		if len(graphemes) == 0 {
+			continue
+		}
+		if f.maxLength == 0 || len(graphemes) < f.minLength {
+			f.minLength = len(graphemes)
+		}
+		if len(graphemes) > f.maxLength {
+			f.maxLength = len(graphemes)
+		}
+	}
+	f.minLength -= maxDistance
+	if f.minLength < 1 {
+		f.minLength = 1
+	}
+	if f.maxLength > 0 {
+		f.maxLength += maxDistance
+	}
+	return f
+}
+
+// finds all hits within the maximum distance of a key of the given trie in the given input string, split into the given graphemes.
+// the trie is walked once per start position, abandoning branches as soon as they exceed the maximum distance.
+// a hit must be closer to its key than the key is long, and overlapping hits of the same key keep the closest one only.
+// the context is checked every contextCheckInterval start positions, returning the hits found so far once it is done.
+func (f *fuzzyIndex) findHits(ctx context.Context, input string, graphemes []utils.Grapheme, t *utils.Trie) ([]hit, error) {
+	var hits []hit
+	for start := range graphemes {
+		if start%contextCheckInterval == 0 && ctx.Err() != nil {
+			return bestFuzzyHits(hits), ctx.Err()
+		}
+		end := start + f.maxLength
+		if end > len(graphemes) {
+			end = len(graphemes)
+		}
+		if f.metric == MetricLevenshtein {
+			hits = append(hits, f.levenshteinHits(input, graphemes[start:end], t)...)
+			continue
+		}
+		for length := f.minLength; start+length <= end; length++ {
+			hits = append(hits, f.multisetHits(input, graphemes[start:start+length], t)...)
+		}
+	}
+
+	best := bestFuzzyHits(hits)
+	utils.Log.WithFields(map[string]interface{}{
+		"inputLength": len(graphemes),
+		"metric":      f.metric,
+		"maxDistance": f.maxDistance,
+		"hitCount":    len(best),
+	}).Debug("Walked trie with bounded edits across input")
+
+	return best, nil
+}
+
+// finds the keys of the given trie within the maximum Levenshtein distance of a prefix of the given graphemes, all starting at the same position.
+// every trie node extends the dynamic programming row of its parent by one character, so all prefixes are compared in a single walk.
+// distances are counted in code points, and only prefixes ending on a grapheme boundary are reported, the closest one per key.
+func (f *fuzzyIndex) levenshteinHits(input string, graphemes []utils.Grapheme, t *utils.Trie) []hit {
+	var runes []rune
+	ends := make(map[int]int) // number of runes of a prefix ending on a grapheme boundary, to the index of its last grapheme
+	for i, g := range graphemes {
+		runes = append(runes, []rune(g.Text)...)
+		ends[len(runes)] = i
+	}
+
+	first := make([]int, len(runes)+1)
+	for j := range first {
+		first[j] = j
+	}
+
+	var hits []hit
+	var key []rune
+	var walk func(node *utils.Node, depth int, row []int)
+	walk = func(node *utils.Node, depth int, row []int) {
+		if node.IsWord {
+			best, bestDistance := -1, f.maxDistance+1
+			for j := 1; j < len(row); j++ {
+				if _, ok := ends[j]; !ok || row[j] >= bestDistance || row[j] >= depth {
+					continue
+				}
+				best, bestDistance = j, row[j]
+			}
+			if best > 0 {
+				last := graphemes[ends[best]]
+				hits = append(hits, hit{
+					offset:   graphemes[0].Offset,
+					text:     input[graphemes[0].Offset:last.End()],
+					key:      string(key),
+					distance: bestDistance,
+				})
+			}
+		}
+
+		for _, r := range sortedRunes(node.Children) {
+			current := make([]int, len(row))
+			current[0] = row[0] + 1
+			smallest := current[0]
+			for j := 1; j < len(current); j++ {
+				cost := 1
+				if runes[j-1] == r {
+					cost = 0
+				}
+				current[j] = minOf(row[j]+1, current[j-1]+1, row[j-1]+cost)
+				if current[j] < smallest {
+					smallest = current[j]
+				}
+			}
+			if smallest > f.maxDistance {
+				continue
+			}
+			key = append(key, r)
+			walk(node.Children[r], depth+1, current)
+			key = key[:len(key)-1]
+		}
+	}
+	walk(t.Root, 0, first)
+	return hits
+}
+
+// finds the keys of the given trie within the maximum multiset distance of the given graphemes, whatever the order of their characters.
+// every character of a key the walk does not find in the candidate counts as an addition, and the characters of the candidate
+// left over at the end of the key as removals. the distance is the larger of the two counts, since a replacement is one addition and one removal.
+// in fixed-ends mode only the characters between the first and the last one are compared as a multiset, see fixedEndsHits.
+func (f *fuzzyIndex) multisetHits(input string, graphemes []utils.Grapheme, t *utils.Trie) []hit {
+	if f.mode == ModeFixedEnds {
+		return f.fixedEndsHits(input, graphemes, t)
+	}
+	last := graphemes[len(graphemes)-1]
+	text := input[graphemes[0].Offset:last.End()]
+	candidateLength := runeCount(graphemes)
+
+	var hits []hit
+	f.walkMultiset(t.Root, graphemes, func(node *utils.Node, key []rune, added int) {
+		if !node.IsWord {
+			return
+		}
+		removed := candidateLength - (len(key) - added)
+		if distance := maxOf(added, removed); distance <= f.maxDistance && distance < len(key) {
+			hits = append(hits, hit{offset: graphemes[0].Offset, text: text, key: string(key), distance: distance})
+		}
+	})
+	return hits
+}
+
+// finds the keys of the given trie within the maximum multiset distance of the given graphemes, keeping their first and last characters in place.
+// fixed-ends keys are the first character, the sorted middle characters and the last character, so the walk follows the first character of
+// the candidate exactly, compares the middle characters as a multiset, and only reports keys ending with the last character of the candidate.
+func (f *fuzzyIndex) fixedEndsHits(input string, graphemes []utils.Grapheme, t *utils.Trie) []hit {
+	first, last := graphemes[0], graphemes[len(graphemes)-1]
+	text := input[first.Offset:last.End()]
+	start := descend(t.Root, first.Text)
+	if start == nil {
+		return nil
+	}
+	if len(graphemes) == 1 {
+		if !start.IsWord {
+			return nil
+		}
+		return []hit{{offset: first.Offset, text: text, key: first.Text}}
+	}
+
+	middle := graphemes[1 : len(graphemes)-1]
+	middleLength := runeCount(middle)
+	endsLength := runeCount(graphemes[:1]) + runeCount(graphemes[len(graphemes)-1:])
+
+	var hits []hit
+	f.walkMultiset(start, middle, func(node *utils.Node, key []rune, added int) {
+		end := descend(node, last.Text)
+		if end == nil || !end.IsWord {
+			return
+		}
+		removed := middleLength - (len(key) - added)
+		if distance := maxOf(added, removed); distance <= f.maxDistance && distance < endsLength+len(key) {
+			hits = append(hits, hit{offset: first.Offset, text: text, key: first.Text + string(key) + last.Text, distance: distance})
+		}
+	})
+	return hits
+}
+
+// walks the trie from the given node, consuming the characters of the given graphemes in any order, and calls visit at every node reached,
+// including the first one, with the runes walked from it and how many of them were not found in the graphemes.
+// branches adding more than the maximum distance are abandoned, and the runes passed to visit are only valid during the call.
+func (f *fuzzyIndex) walkMultiset(start *utils.Node, graphemes []utils.Grapheme, visit func(node *utils.Node, key []rune, added int)) {
+	remaining := make(map[rune]int)
+	for _, g := range graphemes {
+		for _, r := range g.Text {
+			remaining[r]++
+		}
+	}
+
+	var key []rune
+	var walk func(node *utils.Node, added int)
+	walk = func(node *utils.Node, added int) {
+		visit(node, key, added)
+		for _, r := range sortedRunes(node.Children) {
+			key = append(key, r)
+			if remaining[r] > 0 {
+				remaining[r]--
+				walk(node.Children[r], added)
+				remaining[r]++
+			} else if added < f.maxDistance {
+				walk(node.Children[r], added+1)
+			}
+			key = key[:len(key)-1]
+		}
+	}
+	walk(start, 0)
+}
+
+// utility to follow the runes of the given string down the trie from the given node, returning the node reached or nil if there is none.
+func descend(node *utils.Node, s string) *utils.Node {
+	for _, r := range s {
+		child, ok := node.Children[r]
+		if !ok {
+			return nil
+		}
+		node = child
+	}
+	return node
+}
+
+// utility to count the runes of the given graphemes.
+func runeCount(graphemes []utils.Grapheme) int {
+	count := 0
+	for _, g := range graphemes {
+		count += utf8.RuneCountInString(g.Text)
+	}
+	return count
+}
+
+// utility to keep the closest of overlapping hits of the same key, preferring earlier and then shorter hits among equally close ones.
+// the kept hits are ordered by offset, and then by length.
+func bestFuzzyHits(hits []hit) []hit {
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].distance != hits[j].distance {
+			return hits[i].distance < hits[j].distance
+		}
+		if hits[i].offset != hits[j].offset {
+			return hits[i].offset < hits[j].offset
+		}
+		return len(hits[i].text) < len(hits[j].text)
+	})
+
+	var best []hit
+	kept := make(map[string][]hit)
+	for _, h := range hits {
+		overlaps := false
+		for _, other := range kept[h.key] {
+			if h.offset < other.offset+len(other.text) && other.offset < h.offset+len(h.text) {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			kept[h.key] = append(kept[h.key], h)
+			best = append(best, h)
+		}
+	}
+
+	sort.Slice(best, func(i, j int) bool {
+		if best[i].offset != best[j].offset {
+			return best[i].offset < best[j].offset
+		}
+		if len(best[i].text) != len(best[j].text) {
+			return len(best[i].text) < len(best[j].text)
+		}
+		return strings.Compare(best[i].key, best[j].key) < 0
+	})
+	return best
+}
+
+// utility to list the runes of the given trie children in order, so that walks are deterministic.
+func sortedRunes(children map[rune]*utils.Node) []rune {
+	runes := make([]rune, 0, len(children))
+	for r := range children {
+		runes = append(runes, r)
+	}
+	sort.Slice(runes, func(i, j int) bool { return runes[i] < runes[j] })
+	return runes
+}
+
+// utility to get the smallest of the given integers.
+func minOf(first int, rest ...int) int {
+	for _, v := range rest {
+		if v < first {
+			first = v
+		}
+	}
+	return first
+}
+
+// utility to get the larger of two integers.
+func maxOf(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,187 @@
+package wordmatcher
+
+import (
+	"context"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// gappedIndex holds the dictionary keys the gapped engine looks for, along with the characters each of them is made of.
+type gappedIndex struct {
+	mode    Mode
+	maxSpan int
+	words   []gappedWord
+}
+
+// gappedWord is a dictionary key whose characters may be spread across the input, with filler characters between them.
+type gappedWord struct {
+	key    string
+	chars  []string       // characters of the word, in order
+	counts map[string]int // number of occurrences of every character
+	multi  map[string]int // number of occurrences of every character between the first and the last one
+}
+
+// creates a new gappedIndex for the given dictionary words, each split into graphemes, and their keys in the given mode.
+// words sharing a key are looked for once, and words longer than maxSpan characters can never be found, so they are dropped with a warning.
+func newGappedIndex(words [][]utils.Grapheme, keys []string, mode Mode, maxSpan int) *gappedIndex {
+	g := &gappedIndex{mode: mode, maxSpan: maxSpan}
+	seen := make(map[string]struct{})
+	dropped := 0
+	for i, graphemes := range words {
+		if len(graphemes) == 0 {
+			continue
+		}
+		if len(graphemes) > maxSpan {
+			dropped++
+			continue
+		}
+		if _, exists := seen[keys[i]]; exists {
+			continue
+		}
+		seen[keys[i]] = struct{}{}
+
+		word := gappedWord{key: keys[i], chars: make([]string, len(graphemes)), counts: make(map[string]int), multi: make(map[string]int)}
+		for j, grapheme := range graphemes {
+			word.chars[j] = grapheme.Text
+			word.counts[grapheme.Text]++
+			if j > 0 && j < len(graphemes)-1 {
+				word.multi[grapheme.Text]++
+			}
+		}
+		g.words = append(g.words, word)
+	}
+	if dropped > 0 {
+		utils.Log.WithFields(map[string]interface{}{
+			"dropped": dropped,
+			"maxSpan": maxSpan,
+		}).Warn("Dropping words longer than the max span")
+	}
+	return g
+}
+
+// finds all hits in the given input string, split into the given graphemes, whose characters are spread across at most maxSpan characters.
+// a hit is a minimal window holding the characters of a key, in order in exact mode, in any order in anagram mode,
+// and in any order between the first and the last one in fixed-ends mode. the text of a hit is the whole window, filler characters included.
+// the context is checked every contextCheckInterval start positions, returning the hits found so far once it is done.
+func (g *gappedIndex) findHits(ctx context.Context, input string, graphemes []utils.Grapheme) ([]hit, error) {
+	hits := make(map[hit]struct{})
+	chars := make([]string, len(graphemes))
+	for i, grapheme := range graphemes {
+		chars[i] = grapheme.Text
+	}
+
+	for _, word := range g.words {
+		ends := make([]int, len(chars))
+		for start := range chars {
+			if start%contextCheckInterval == 0 && ctx.Err() != nil {
+				return sortedHits(hits), ctx.Err()
+			}
+			ends[start] = g.windowEnd(word, chars, start)
+		}
+
+		// a window is minimal unless a window starting after it ends no later than it does.
+		nextEnd := len(chars)
+		for start := len(chars) - 1; start >= 0; start-- {
+			end := ends[start]
+			if end < 0 {
+				continue
+			}
+			if end < nextEnd {
+				h := hit{offset: graphemes[start].Offset, text: input[graphemes[start].Offset:graphemes[end].End()], key: word.key}
+				hits[h] = struct{}{}
+				nextEnd = end
+			}
+		}
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"inputLength": len(graphemes),
+		"maxSpan":     g.maxSpan,
+		"keyCount":    len(g.words),
+		"hitCount":    len(hits),
+	}).Debug("Searched input for gapped words")
+
+	return sortedHits(hits), nil
+}
+
+// finds the index of the last character of the shortest window starting at the given position that holds the given word, -1 if none does.
+func (g *gappedIndex) windowEnd(word gappedWord, chars []string, start int) int {
+	limit := start + g.maxSpan
+	if limit > len(chars) {
+		limit = len(chars)
+	}
+
+	switch {
+	case g.mode == ModeAnagram:
+		return multisetWindowEnd(word, chars[start:limit], start)
+	case g.mode == ModeExact || len(word.chars) <= 3:
+		return subsequenceWindowEnd(word.chars, chars[start:limit], start)
+	default:
+		return fixedEndsWindowEnd(word, chars[start:limit], start)
+	}
+}
+
+// utility to find the end of the shortest window at the start of the given characters holding the given word's characters in order.
+// the window must start with the word's first character, the returned index is offset by start.
+func subsequenceWindowEnd(word []string, chars []string, start int) int {
+	next := 0
+	for i, c := range chars {
+		if c == word[next] {
+			next++
+			if next == len(word) {
+				return start + i
+			}
+		} else if i == 0 {
+			return -1
+		}
+	}
+	return -1
+}
+
+// utility to find the end of the shortest window at the start of the given characters holding the given word's characters in any order.
+// the window must start with one of the word's characters, the returned index is offset by start.
+func multisetWindowEnd(word gappedWord, chars []string, start int) int {
+	if len(chars) == 0 || word.counts[chars[0]] == 0 {
+		return -1
+	}
+	missing := make(map[string]int, len(word.counts))
+	for c, count := range word.counts {
+		missing[c] = count
+	}
+	remaining := len(word.chars)
+	for i, c := range chars {
+		if missing[c] > 0 {
+			missing[c]--
+			remaining--
+			if remaining == 0 {
+				return start + i
+			}
+		}
+	}
+	return -1
+}
+
+// utility to find the end of the shortest window at the start of the given characters starting and ending with the word's first and last characters,
+// and holding the characters in between in any order. the returned index is offset by start.
+func fixedEndsWindowEnd(word gappedWord, chars []string, start int) int {
+	last := word.chars[len(word.chars)-1]
+	if len(chars) == 0 || chars[0] != word.chars[0] {
+		return -1
+	}
+	missing := make(map[string]int, len(word.multi))
+	for c, count := range word.multi {
+		missing[c] = count
+	}
+	remaining := len(word.chars) - 2
+	for i := 1; i < len(chars); i++ {
+		c := chars[i]
+		if remaining == 0 && c == last {
+			return start + i
+		}
+		if missing[c] > 0 {
+			missing[c]--
+			remaining--
+		}
+	}
+	return -1
+}
@@ -0,0 +1,104 @@
+package wordmatcher
+
+import (
+	"context"
+	"sort"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// Match describes a single occurrence of a dictionary word in an input line.
+type Match struct {
+	Word       string `json:"word"`                 // dictionary word that was matched
+	Text       string `json:"text"`                 // text of the occurrence, as it appears in the input line
+	Offset     int    `json:"offset"`               // byte offset of the occurrence within the input line
+	Line       int    `json:"line"`                 // line number the occurrence was found on
+	Exact      bool   `json:"exact"`                // whether the occurrence is the dictionary word itself, rather than a scramble of it
+	Dictionary string `json:"dictionary,omitempty"` // name of the dictionary the word comes from, when several are used
+	Distance   int    `json:"distance,omitempty"`   // distance between the occurrence and the dictionary word, only set by the fuzzy engine
+}
+
+// hit is a substring of an input line whose key is present in the trie, before it is resolved to dictionary words.
+// the fuzzy engine also finds substrings near a key, distance tells how far they are from it.
+type hit struct {
+	offset   int
+	text     string
+	key      string
+	distance int
+}
+
+// MatchLine finds every occurrence of every dictionary word in the given input line, ordered by offset.
+// an occurrence whose key is shared by several dictionary words yields one Match per dictionary word.
+// lines are matched once normalized, but the text and offset of every match refer to the given input line, so they can be used to slice it.
+// once the given context is done the line is only partly scanned, and the engine may have stopped anywhere in it: the matches returned
+// along with the context's error are those of the hits it found until then, ordered by offset, and may miss occurrences at any offset.
+func (m *Matcher) MatchLine(ctx context.Context, line int, input string) ([]Match, error) {
+	normalized, offsets := m.normalizer.NormalizeWithOffsets(input)
+	hits, err := m.findHits(ctx, normalized)
+	var matches []Match
+	for _, h := range hits {
+		start, end := offsets.Span(h.offset, h.offset+len(h.text))
+		for _, word := range m.trie.Lookup(h.key) {
+			matches = append(matches, Match{
+				Word:       word,
+				Text:       input[start:end],
+				Offset:     start,
+				Line:       line,
+				Exact:      h.text == m.normalizedWords[word],
+				Dictionary: m.dictionary,
+				Distance:   h.distance,
+			})
+		}
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"line":       line,
+		"matchCount": len(matches),
+	}).Debug("Resolved line matches to dictionary words")
+
+	return matches, err
+}
+
+// UniqueWords returns the distinct dictionary words present in the given matches, in order of first occurrence.
+// a word present in several dictionaries is returned once.
+func UniqueWords(matches []Match) []string {
+	seen := make(map[string]struct{})
+	words := []string{}
+	for _, match := range matches {
+		if _, exists := seen[match.Word]; exists {
+			continue
+		}
+		seen[match.Word] = struct{}{}
+		words = append(words, match.Word)
+	}
+	return words
+}
+
+// utility to order a set of hits by offset, then by length, and then by key.
+func sortedHits(set map[hit]struct{}) []hit {
+	hits := make([]hit, 0, len(set))
+	for h := range set {
+		hits = append(hits, h)
+	}
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].offset != hits[j].offset {
+			return hits[i].offset < hits[j].offset
+		}
+		if len(hits[i].text) != len(hits[j].text) {
+			return len(hits[i].text) < len(hits[j].text)
+		}
+		return hits[i].key < hits[j].key
+	})
+	return hits
+}
+
+// MatchesOf returns the given matches of words from the dictionary with the given name, in order.
+func MatchesOf(matches []Match, dictionary string) []Match {
+	var filtered []Match
+	for _, match := range matches {
+		if match.Dictionary == dictionary {
+			filtered = append(filtered, match)
+		}
+	}
+	return filtered
+}
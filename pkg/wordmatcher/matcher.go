@@ -1,71 +1,292 @@
 package wordmatcher
 
 import (
-	"sort"
-	"strings"
+	"context"
 	"sync"
 
 	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
 	"github.com/1x-eng/cipherlex/pkg/utils"
 )
 
-// Matcher is a struct that holds the trie and chunk size.
+// Matcher is a struct that holds the trie, chunk size, the key function of the selected mode and the selected engine.
+// the trie node of every key lists the dictionary words sharing it, so hits resolve to dictionary words with a single lookup.
+// the trie is a utils.Trie by default, or a utils.CompactTrie for large dictionaries, nodes is only set for the former.
+// chunks are processed by a worker pool shared by every line the Matcher is used for, release it with Close.
+// dictionary words and input lines are normalized before matching, and all lengths are measured in user-perceived characters.
+// when punctuation is ignored, words and candidate substrings are formed from their letters, digits and symbols alone.
 type Matcher struct {
-	trie      *utils.Trie
-	chunkSize int
-	dictWords []string
+	trie              utils.WordTrie
+	nodes             *utils.Trie
+	automaton         *utils.Automaton
+	chunkSize         int
+	overlap           int
+	dictWords         []string
+	normalizedWords   map[string]string
+	duplicateKeys     map[string][]string
+	keyFunc           keyFunc
+	prefixFunc        prefixFunc
+	maxCounts         graphemeCounts
+	normalizer        *utils.Normalizer
+	ignorePunctuation bool
+	dictionary        string
+	mode              Mode
+	engine            Engine
+	window            *windowIndex
+	fuzzy             *fuzzyIndex
+	gapped            *gappedIndex
+	pool              *utils.WorkerPool
 }
 
 // creates a new Matcher with the given dictionary and configuration.
 func NewMatcher(dict []string, cfg config.AppConfig, chunkSize int) *Matcher {
-	m := &Matcher{
-		trie:      utils.NewTrie(),
-		chunkSize: chunkSize,
-		dictWords: dict,
+	m := newMatcher(cfg, chunkSize)
+	words := make([]CompiledWord, len(dict))
+	for i, word := range dict {
+		words[i] = m.compile(dictionary.Entry{Word: word})
+	}
+	m.insert(words, cfg)
+	return m
+}
+
+// creates a new Matcher with the given configuration and no dictionary words yet, falling back to defaults for unknown or conflicting settings.
+func newMatcher(cfg config.AppConfig, chunkSize int) *Matcher {
+	mode, err := ParseMode(cfg.Mode)
+	if err != nil {
+		utils.Log.WithError(err).Warn("Falling back to anagram match mode")
+		mode = ModeAnagram
+	}
+	engine, err := ParseEngine(cfg.Engine)
+	if err != nil {
+		utils.Log.WithError(err).Warn("Falling back to trie match engine")
+		engine = EngineTrie
+	}
+	if err := CheckEngine(engine, mode); err != nil {
+		utils.Log.WithError(err).Warn("Falling back to trie match engine")
+		engine = EngineTrie
+	}
+	impl, err := utils.ParseTrieImpl(cfg.TrieImpl)
+	if err != nil {
+		utils.Log.WithError(err).Warn("Falling back to map trie")
+		impl = utils.TrieMap
+	}
+	if err := CheckTrieImpl(impl, engine); err != nil {
+		utils.Log.WithError(err).Warn("Falling back to map trie")
+		impl = utils.TrieMap
 	}
-	for _, word := range dict {
-		key := generateKey(word)
+
+	trie := utils.NewWordTrie(impl)
+	nodes, _ := trie.(*utils.Trie)
+	return &Matcher{
+		trie:              trie,
+		nodes:             nodes,
+		chunkSize:         chunkSize,
+		keyFunc:           mode.keyFunc(),
+		prefixFunc:        mode.prefixFunc(),
+		normalizer:        utils.NewNormalizer(cfg.TextConfig),
+		ignorePunctuation: cfg.IgnorePunctuation,
+		mode:              mode,
+		engine:            engine,
+	}
+}
+
+// inserts the given compiled words into the trie and prepares the selected engine, words compiled in another mode are keyed again.
+func (m *Matcher) insert(words []CompiledWord, cfg config.AppConfig) {
+	m.dictWords = make([]string, 0, len(words))
+	m.normalizedWords = make(map[string]string, len(words))
+	wordGraphemes := make([][]utils.Grapheme, 0, len(words))
+	keys := make([]string, 0, len(words))
+	for _, w := range words {
+		key := w.Key
+		if w.Mode != m.mode {
+			key = m.keyFunc(w.Graphemes)
+		}
 
 		utils.Log.WithFields(map[string]interface{}{
-			"word": word,
-			"key":  key,
+			"word":       w.Entry.Word,
+			"normalized": w.Normalized,
+			"key":        key,
 		}).Debug("Inserting word into trie")
 
-		m.trie.Insert(key)
+		m.dictWords = append(m.dictWords, w.Entry.Word)
+		m.normalizedWords[w.Entry.Word] = w.Normalized
+		wordGraphemes = append(wordGraphemes, w.Graphemes)
+		keys = append(keys, key)
+		m.trie.InsertWithSource(key, w.Entry.Word)
+	}
+	m.duplicateKeys = findDuplicateKeys(m.trie, keys)
+	if len(m.duplicateKeys) > 0 {
+		utils.Log.WithFields(map[string]interface{}{
+			"duplicateKeys": m.duplicateKeys,
+		}).Warn("Several dictionary words share a key, every occurrence of one of them counts as an occurrence of all of them")
+	}
+	m.overlap = chunkOverlap(wordGraphemes)
+
+	stats := m.trie.Stats()
+	utils.Log.WithFields(map[string]interface{}{
+		"keys":         stats.Words,
+		"nodes":        stats.Nodes,
+		"maxDepth":     stats.MaxDepth,
+		"averageDepth": stats.AverageDepth,
+	}).Debug("Built trie")
+
+	switch m.engine {
+	case EngineWindow:
+		m.window = newWindowIndex(wordGraphemes)
+	case EngineAhoCorasick:
+		m.automaton = m.nodes.BuildAutomaton()
+	case EngineFuzzy:
+		metric, err := ParseMetric(cfg.DistanceMetric, m.mode)
+		if err == nil {
+			err = CheckMetric(metric, m.mode)
+		}
+		if err != nil {
+			utils.Log.WithError(err).Warn("Falling back to the default distance metric")
+			metric = defaultMetric(m.mode)
+		}
+		m.fuzzy = newFuzzyIndex(wordGraphemes, m.mode, metric, cfg.MaxDistance)
+	case EngineGapped:
+		m.gapped = newGappedIndex(wordGraphemes, keys, m.mode, cfg.MaxSpan)
+	default:
+		if m.mode == ModeAnagram {
+			m.maxCounts = maxGraphemeCounts(wordGraphemes)
+		}
+		m.pool = utils.NewWorkerPool(cfg.Workers)
 	}
-	return m
 }
 
-// finds all matches in the given input string, concurrently and in chunks, returning a map of matches.
-func (m *Matcher) FindMatches(input string) map[string]struct{} {
+// utility to normalize the word of the given entry and split it into graphemes, computing its key in the mode of the Matcher.
+func (m *Matcher) compile(entry dictionary.Entry) CompiledWord {
+	normalized := m.normalizer.Normalize(entry.Word)
+	graphemes := m.graphemesOf(normalized)
+	return CompiledWord{Entry: entry, Mode: m.mode, Normalized: normalized, Graphemes: graphemes, Key: m.keyFunc(graphemes)}
+}
+
+// Close stops the workers of the Matcher, it must not be used afterwards.
+func (m *Matcher) Close() {
+	if m.pool != nil {
+		m.pool.Close()
+	}
+}
+
+// finds all matches in the given input string using the selected engine, returning a map of matches.
+// matches are substrings of the given input string, found once it is normalized.
+// if the given context is done before the scan completes, the map only holds the substrings of the hits found until then, and the context's error is returned with it.
+func (m *Matcher) FindMatches(ctx context.Context, input string) (map[string]struct{}, error) {
+	normalized, offsets := m.normalizer.NormalizeWithOffsets(input)
+	hits, err := m.findHits(ctx, normalized)
 	matches := make(map[string]struct{})
-	chunks := splitString(input, m.chunkSize)
+	for _, h := range hits {
+		start, end := offsets.Span(h.offset, h.offset+len(h.text))
+		matches[input[start:end]] = struct{}{}
+	}
+	return matches, err
+}
 
-	var wg sync.WaitGroup
-	matchMutex := &sync.Mutex{} // Mutex for safely updating 'matches'
+// DuplicateKeys returns the keys shared by more than one dictionary word, along with the words sharing them in dictionary order.
+// such words are indistinguishable in the selected mode, e.g. anagrams of each other in anagram mode.
+func (m *Matcher) DuplicateKeys() map[string][]string {
+	return m.duplicateKeys
+}
+
+// utility to collect the given keys of the trie that more than one dictionary word was inserted under.
+func findDuplicateKeys(t utils.WordTrie, keys []string) map[string][]string {
+	duplicates := make(map[string][]string)
+	for _, key := range keys {
+		if words := t.Lookup(key); len(words) > 1 {
+			duplicates[key] = words
+		}
+	}
+	return duplicates
+}
 
-	for _, chunk := range chunks {
+// utility to split a normalized string into the graphemes words and candidate substrings are formed from.
+func (m *Matcher) graphemesOf(s string) []utils.Grapheme {
+	graphemes := utils.Graphemes(s)
+	if m.ignorePunctuation {
+		return utils.WithoutPunctuation(graphemes)
+	}
+	return graphemes
+}
+
+// finds all hits in the given, already normalized, input string using the selected engine, ordered by offset and length.
+// with punctuation ignored, the text of a hit still spans any punctuation between its first and last character.
+func (m *Matcher) findHits(ctx context.Context, input string) ([]hit, error) {
+	graphemes := m.graphemesOf(input)
+	switch m.engine {
+	case EngineWindow:
+		return m.window.findHits(ctx, input, graphemes, m.trie, m.keyFunc)
+	case EngineAhoCorasick:
+		return findExactHits(ctx, input, graphemes, m.automaton)
+	case EngineFuzzy:
+		return m.fuzzy.findHits(ctx, input, graphemes, m.nodes)
+	case EngineGapped:
+		return m.gapped.findHits(ctx, input, graphemes)
+	default:
+		return m.findHitsInChunks(ctx, input, graphemes)
+	}
+}
+
+// finds all hits in the given input string, split into the given graphemes, concurrently and in chunks, on the worker pool.
+// every worker collects hits into its own local set, and the sets are reduced once all chunks are processed.
+// no further chunks are submitted, and running ones return early, once the given context is done.
+func (m *Matcher) findHitsInChunks(ctx context.Context, input string, graphemes []utils.Grapheme) ([]hit, error) {
+	chunks := splitGraphemes(graphemes, m.chunkSize, m.overlap)
+	localHits := make([]map[hit]struct{}, m.pool.Size())
+
+	var wg sync.WaitGroup
+	for _, c := range chunks {
+		c := c
 		wg.Add(1)
-		go func(c string) {
+		err := m.pool.Submit(ctx, func(worker int) {
 			defer wg.Done()
-			localMatches := processChunk(c, m.trie)
-			mergeMatches(matches, localMatches, matchMutex)
-		}(chunk)
+			if localHits[worker] == nil {
+				localHits[worker] = make(map[hit]struct{})
+			}
+			for _, h := range processChunk(ctx, c, input, m.trie, m.keyFunc, m.prefixFunc, m.maxCounts) {
+				localHits[worker][h] = struct{}{}
+			}
+		})
+		if err != nil {
+			wg.Done()
+			break
+		}
 	}
 
 	wg.Wait()
-	return matches
+	return reduceHits(localHits), ctx.Err()
 }
 
-// utility to process a chunk of the input string, finding all matches in the given trie.
-func processChunk(chunk string, t *utils.Trie) map[string]struct{} {
-	localMatches := make(map[string]struct{})
+// utility to process a chunk of the given input string, finding all hits in the given trie using the given key function.
+// the chunk is a run of the input's graphemes, so substrings never split a character.
+// with a prefix function, substrings are no longer extended once no key in the trie has their prefix,
+// and with character counts, once they hold a character more often than any dictionary word does.
+// the hits found so far are returned once the given context is done.
+func processChunk(ctx context.Context, chunk []utils.Grapheme, input string, t utils.WordTrie, keyFunc keyFunc, prefixFunc prefixFunc, maxCounts graphemeCounts) []hit {
+	var localHits []hit
+	counts := make(map[string]int)
 	for i := 0; i < len(chunk); i++ {
+		if ctx.Err() != nil {
+			return localHits
+		}
+		for g := range counts {
+			delete(counts, g)
+		}
 		for j := i + 1; j <= len(chunk); j++ {
-			substr := chunk[i:j]
-			key := generateKey(substr)
+			if prefixFunc != nil && !t.HasPrefix(prefixFunc(chunk[i:j])) {
+				break
+			}
+			if maxCounts != nil {
+				g := chunk[j-1].Text
+				counts[g]++
+				if counts[g] > maxCounts[g] {
+					break
+				}
+			}
+			substr := input[chunk[i].Offset:chunk[j-1].End()]
+			key := keyFunc(chunk[i:j])
 			if t.Find(key) {
-				localMatches[substr] = struct{}{}
+				localHits = append(localHits, hit{offset: chunk[i].Offset, text: substr, key: key})
 			}
 
 			utils.Log.WithFields(map[string]interface{}{
@@ -77,53 +298,71 @@ func processChunk(chunk string, t *utils.Trie) map[string]struct{} {
 
 		}
 	}
-	return localMatches
+	return localHits
 }
 
-// utility to merge local matches processed concurrently into global matches, using a mutex for safety.
-func mergeMatches(global, local map[string]struct{}, mutex *sync.Mutex) {
-	mutex.Lock()
-	defer mutex.Unlock()
-	for key := range local {
-		global[key] = struct{}{}
+// utility to reduce the local hit sets of all workers into a single ordered slice.
+// hits found twice in the overlapping region of adjacent chunks collapse into a single entry.
+func reduceHits(localHits []map[hit]struct{}) []hit {
+	hits := make(map[hit]struct{})
+	for _, local := range localHits {
+		for h := range local {
+			hits[h] = struct{}{}
+		}
 	}
+	return sortedHits(hits)
 }
 
-// utility to generate a key for a given word, by sorting letters to account for anagrams.
-func generateKey(word string) string {
-	chars := strings.Split(word, "")
-	sort.Strings(chars)
-	return strings.Join(chars, "")
+// utility to determine how far consecutive chunks must overlap, so that no dictionary word can straddle a chunk boundary unseen.
+func chunkOverlap(words [][]utils.Grapheme) int {
+	longest := 0
+	for _, graphemes := range words {
+		if len(graphemes) > longest {
+			longest = len(graphemes)
+		}
+	}
+	if longest == 0 {
+		return 0
+	}
+	return longest - 1
 }
 
-// utility to split a string into chunks of the given size.
-func splitString(input string, chunkSize int) []string {
-	var chunks []string
-	for i := 0; i < len(input); i += chunkSize {
-		end := i + chunkSize
-		if end > len(input) {
-			end = len(input)
+// utility to split graphemes into chunks of the given size, each extended by overlap graphemes into the next chunk.
+// every run of at most overlap+1 graphemes is fully contained in at least one chunk.
+func splitGraphemes(graphemes []utils.Grapheme, chunkSize, overlap int) [][]utils.Grapheme {
+	if chunkSize <= 0 {
+		chunkSize = len(graphemes)
+	}
+
+	var chunks [][]utils.Grapheme
+	for i := 0; i < len(graphemes); i += chunkSize {
+		end := i + chunkSize + overlap
+		if end > len(graphemes) {
+			end = len(graphemes)
+		}
+		chunks = append(chunks, graphemes[i:end])
+		if end == len(graphemes) {
+			break
 		}
-		chunks = append(chunks, input[i:end])
 	}
 
 	utils.Log.WithFields(map[string]interface{}{
-		"input":     input,
-		"chunkSize": chunkSize,
-		"chunks":    chunks,
+		"length":     len(graphemes),
+		"chunkSize":  chunkSize,
+		"overlap":    overlap,
+		"chunkCount": len(chunks),
 	}).Debug("Split input string into chunks")
 
 	return chunks
 }
 
-// counts the unique occurrences of dictionary words in the matches.
+// counts the unique occurrences of dictionary words in the matches, which are normalized just like input lines.
 func (m *Matcher) CountUniqueMatches(matches map[string]struct{}) int {
 	uniqueWords := make(map[string]struct{})
 	for match := range matches {
-		for _, word := range m.dictWords {
-			if generateKey(word) == generateKey(match) {
-				uniqueWords[word] = struct{}{}
-			}
+		matchKey := m.keyFunc(m.graphemesOf(m.normalizer.Normalize(match)))
+		for _, word := range m.trie.Lookup(matchKey) {
+			uniqueWords[word] = struct{}{}
 		}
 	}
 
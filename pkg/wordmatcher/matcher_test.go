@@ -1,9 +1,14 @@
 package wordmatcher
 
 import (
+	"context"
+	"math/rand"
+	"sync"
 	"testing"
 
 	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/utils"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -13,9 +18,11 @@ func TestMatcher_FindMatches(t *testing.T) {
 	chunkSize := 10
 
 	matcher := NewMatcher(dict, config.AppConfig{}, chunkSize)
+	defer matcher.Close()
 	input := "aapxjdnrbtvldptfzbbdbbzxtndrvjblnzjfpvhdhhpxjdnrbt"
 
-	matches := matcher.FindMatches(input)
+	matches, err := matcher.FindMatches(context.Background(), input)
+	assert.NoError(t, err)
 
 	for key := range matches {
 		assert.Contains(t, expectedMatches, key, "Expected matches should contain word")
@@ -27,10 +34,926 @@ func TestMatcher_CountUniqueMatches(t *testing.T) {
 	chunkSize := 10
 
 	matcher := NewMatcher(dict, config.AppConfig{}, chunkSize)
+	defer matcher.Close()
 	input := "aapxjdnrbtvldptfzbbdbbzxtndrvjblnzjfpvhdhhpxjdnrbt"
 
-	matches := matcher.FindMatches(input)
+	matches, err := matcher.FindMatches(context.Background(), input)
+	assert.NoError(t, err)
 	uniqueCount := matcher.CountUniqueMatches(matches)
 
 	assert.Equal(t, 4, uniqueCount, "Unique count should be 4")
 }
+
+func TestMatcher_MatchLine(t *testing.T) {
+	dict := []string{"axpaj", "apxaj", "dnrbt", "pjxdn", "abd"}
+	matcher := NewMatcher(dict, config.AppConfig{}, 10)
+	defer matcher.Close()
+
+	matches, err := matcher.MatchLine(context.Background(), 3, "aapxjdnrbtvldptfzbbdbbzxtndrvjblnzjfpvhdhhpxjdnrbt")
+	assert.NoError(t, err)
+
+	assert.Equal(t, Match{Word: "axpaj", Text: "aapxj", Offset: 0, Line: 3, Exact: false}, matches[0])
+	assert.Equal(t, Match{Word: "apxaj", Text: "aapxj", Offset: 0, Line: 3, Exact: false}, matches[1])
+
+	var dnrbtOffsets []int
+	for _, match := range matches {
+		assert.Equal(t, 3, match.Line, "Every match should carry the line number")
+		if match.Word == "dnrbt" {
+			dnrbtOffsets = append(dnrbtOffsets, match.Offset)
+			assert.True(t, match.Exact, "Unscrambled occurrences should be exact")
+		}
+	}
+	assert.Equal(t, []int{5, 45}, dnrbtOffsets, "Every occurrence should be reported at its byte offset")
+	assert.ElementsMatch(t, []string{"axpaj", "apxaj", "dnrbt", "pjxdn"}, UniqueWords(matches))
+}
+
+func TestMatcher_MatchLineWindowEngine(t *testing.T) {
+	dict := []string{"axpaj", "apxaj", "dnrbt", "pjxdn", "abd"}
+	input := "aapxjdnrbtvldptfzbbdbbzxtndrvjblnzjfpvhdhhpxjdnrbt"
+
+	trieMatcher := NewMatcher(dict, config.AppConfig{}, 10)
+	defer trieMatcher.Close()
+	windowMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Engine: string(EngineWindow)}}, 10)
+	defer windowMatcher.Close()
+
+	trieMatches, err := trieMatcher.MatchLine(context.Background(), 1, input)
+	assert.NoError(t, err)
+	windowMatches, err := windowMatcher.MatchLine(context.Background(), 1, input)
+	assert.NoError(t, err)
+
+	assert.Equal(t, trieMatches, windowMatches, "Engines should report identical matches")
+}
+
+func TestMatcher_FixedEndsMode(t *testing.T) {
+	dict := []string{"this", "is", "aproblem", "test"}
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(ModeFixedEnds)}}
+
+	matcher := NewMatcher(dict, cfg, 10)
+	defer matcher.Close()
+	// "tihs" and "tset" keep their ends in place, "aporblem" does too, "si" is a full anagram of "is".
+	matches, err := matcher.FindMatches(context.Background(), "tihsaporblemtsetsi")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 3, matcher.CountUniqueMatches(matches), "Only words with fixed first and last letters should count")
+	assert.NotContains(t, matches, "si", "Swapped ends should not match in fixed-ends mode")
+}
+
+func TestMatcher_AnagramModeMatchesSwappedEnds(t *testing.T) {
+	dict := []string{"this", "is", "aproblem", "test"}
+
+	matcher := NewMatcher(dict, config.AppConfig{}, 10)
+	defer matcher.Close()
+	matches, err := matcher.FindMatches(context.Background(), "tihsaporblemtsetsi")
+	assert.NoError(t, err)
+
+	assert.Equal(t, 4, matcher.CountUniqueMatches(matches), "Anagram mode should match any permutation")
+}
+
+func TestParseMode(t *testing.T) {
+	mode, err := ParseMode("")
+	assert.NoError(t, err)
+	assert.Equal(t, ModeAnagram, mode)
+
+	mode, err = ParseMode("fixed-ends")
+	assert.NoError(t, err)
+	assert.Equal(t, ModeFixedEnds, mode)
+
+	_, err = ParseMode("bogus")
+	assert.Error(t, err)
+}
+
+func TestMatcher_FindMatchesAcrossChunkBoundaries(t *testing.T) {
+	dict := []string{"abcde"}
+	matcher := NewMatcher(dict, config.AppConfig{}, 3)
+	defer matcher.Close()
+
+	// "edcba" straddles the boundaries of every 3 byte chunk.
+	matches, err := matcher.FindMatches(context.Background(), "xxedcbaxx")
+	assert.NoError(t, err)
+
+	assert.Contains(t, matches, "edcba", "Words spanning chunk boundaries should be matched")
+}
+
+// TestMatcher_FindMatchesChunkedEqualsWholeLine checks that chunking never changes the result of a whole-line scan.
+func TestMatcher_FindMatchesChunkedEqualsWholeLine(t *testing.T) {
+	rng := rand.New(rand.NewSource(42))
+
+	for iteration := 0; iteration < 200; iteration++ {
+		dict := make([]string, 1+rng.Intn(6))
+		for i := range dict {
+			dict[i] = randomString(rng, 2+rng.Intn(6))
+		}
+		line := randomString(rng, 1+rng.Intn(80))
+		chunkSize := 1 + rng.Intn(20)
+
+		matcher := NewMatcher(dict, config.AppConfig{}, chunkSize)
+		defer matcher.Close()
+		expected := make(map[string]struct{})
+		for _, h := range processChunk(context.Background(), utils.Graphemes(line), line, matcher.trie, matcher.keyFunc, matcher.prefixFunc, matcher.maxCounts) {
+			expected[h.text] = struct{}{}
+		}
+
+		matches, err := matcher.FindMatches(context.Background(), line)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, matches,
+			"dict=%v line=%q chunkSize=%d", dict, line, chunkSize)
+	}
+}
+
+// utility to generate a random string over a small alphabet, so that matches are likely.
+func randomString(rng *rand.Rand, length int) string {
+	const alphabet = "abcd"
+	b := make([]byte, length)
+	for i := range b {
+		b[i] = alphabet[rng.Intn(len(alphabet))]
+	}
+	return string(b)
+}
+
+// TestMatcher_WindowEngineEqualsTrieEngine checks that both engines agree in every mode.
+func TestMatcher_WindowEngineEqualsTrieEngine(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+
+	for _, mode := range []Mode{ModeAnagram, ModeFixedEnds} {
+		for iteration := 0; iteration < 100; iteration++ {
+			dict := make([]string, 1+rng.Intn(6))
+			for i := range dict {
+				dict[i] = randomString(rng, 2+rng.Intn(6))
+			}
+			line := randomString(rng, 1+rng.Intn(80))
+
+			trieMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(mode), Engine: string(EngineTrie)}}, 10)
+			defer trieMatcher.Close()
+			windowMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(mode), Engine: string(EngineWindow)}}, 10)
+			defer windowMatcher.Close()
+
+			trieMatches, err := trieMatcher.FindMatches(context.Background(), line)
+			assert.NoError(t, err)
+			windowMatches, err := windowMatcher.FindMatches(context.Background(), line)
+			assert.NoError(t, err)
+
+			assert.Equal(t, trieMatches, windowMatches,
+				"mode=%s dict=%v line=%q", mode, dict, line)
+		}
+	}
+}
+
+// TestMatcher_PrefixPruningKeepsHits checks that stopping substring scans once no key has their prefix, or once they hold a character
+// more often than any word, never loses a hit, in every mode.
+func TestMatcher_PrefixPruningKeepsHits(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+
+	for _, mode := range []Mode{ModeAnagram, ModeFixedEnds, ModeExact} {
+		for iteration := 0; iteration < 100; iteration++ {
+			dict := make([]string, 1+rng.Intn(6))
+			for i := range dict {
+				dict[i] = randomString(rng, 2+rng.Intn(6))
+			}
+			line := randomString(rng, 1+rng.Intn(80))
+
+			matcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(mode)}}, len(line))
+			defer matcher.Close()
+			assert.True(t, matcher.prefixFunc != nil || matcher.maxCounts != nil, "mode=%s prunes substring scans", mode)
+			graphemes := utils.Graphemes(line)
+			expected := processChunk(context.Background(), graphemes, line, matcher.trie, matcher.keyFunc, nil, nil)
+			pruned := processChunk(context.Background(), graphemes, line, matcher.trie, matcher.keyFunc, matcher.prefixFunc, matcher.maxCounts)
+			assert.Equal(t, expected, pruned, "mode=%s dict=%v line=%q", mode, dict, line)
+		}
+	}
+}
+
+func TestMaxGraphemeCounts(t *testing.T) {
+	words := [][]utils.Grapheme{utils.Graphemes("banana"), utils.Graphemes("cab"), utils.Graphemes("e\u0301te\u0301")}
+	assert.Equal(t, graphemeCounts{"a": 3, "b": 1, "c": 1, "n": 2, "e\u0301": 2, "t": 1}, maxGraphemeCounts(words))
+}
+
+func TestMatcher_FindMatchesCanceled(t *testing.T) {
+	dict := []string{"axpaj", "dnrbt"}
+	input := "aapxjdnrbtvldptfzbbdbbzxtndrvjblnzjfpvhdhhpxjdnrbt"
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, engine := range []Engine{EngineTrie, EngineWindow} {
+		matcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Engine: string(engine)}}, 10)
+		defer matcher.Close()
+
+		matches, err := matcher.FindMatches(ctx, input)
+		assert.ErrorIs(t, err, context.Canceled, "engine=%s", engine)
+		assert.Empty(t, matches, "No chunk should be processed once the context is done, engine=%s", engine)
+	}
+}
+
+func BenchmarkFindMatches_TrieEngine(b *testing.B) {
+	benchmarkFindMatches(b, ModeAnagram, EngineTrie, 2000)
+}
+
+func BenchmarkFindMatches_WindowEngine(b *testing.B) {
+	benchmarkFindMatches(b, ModeAnagram, EngineWindow, 2000)
+}
+
+func BenchmarkFindMatches_WindowEngineLongLine(b *testing.B) {
+	benchmarkFindMatches(b, ModeAnagram, EngineWindow, 100000)
+}
+
+func BenchmarkFindMatches_ExactTrieEngine(b *testing.B) {
+	benchmarkFindMatches(b, ModeExact, EngineTrie, 2000)
+}
+
+func BenchmarkFindMatches_AhoCorasickEngine(b *testing.B) {
+	benchmarkFindMatches(b, ModeExact, EngineAhoCorasick, 2000)
+}
+
+func BenchmarkFindMatches_AhoCorasickEngineLongLine(b *testing.B) {
+	benchmarkFindMatches(b, ModeExact, EngineAhoCorasick, 100000)
+}
+
+func BenchmarkFindMatches_FuzzyEngine(b *testing.B) {
+	benchmarkFindMatches(b, ModeAnagram, EngineFuzzy, 100)
+}
+
+func BenchmarkFindMatches_CompactTrie(b *testing.B) {
+	rng := rand.New(rand.NewSource(1))
+	dict := make([]string, 100)
+	for i := range dict {
+		dict[i] = randomString(rng, 2+rng.Intn(19))
+	}
+	line := randomString(rng, 2000)
+
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{TrieImpl: string(utils.TrieCompact)}}
+	matcher := NewMatcher(dict, cfg, 100)
+	defer matcher.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = matcher.FindMatches(context.Background(), line)
+	}
+}
+
+func BenchmarkFindMatches_GappedEngine(b *testing.B) {
+	benchmarkFindMatches(b, ModeAnagram, EngineGapped, 2000)
+}
+
+func BenchmarkMatchLine_ExactPolicyTrieEngine(b *testing.B) {
+	benchmarkExactPolicy(b, "")
+}
+
+func BenchmarkMatchLine_ExactPolicyAhoCorasickEngine(b *testing.B) {
+	benchmarkExactPolicy(b, EngineAhoCorasick)
+}
+
+// utility to benchmark MatchLine over a dictionary whose words all have an exact policy, in the default anagram mode, with the given exact engine.
+// without an exact engine the exact words are found by the configured trie engine.
+func benchmarkExactPolicy(b *testing.B, exactEngine Engine) {
+	rng := rand.New(rand.NewSource(1))
+	entries := make([]dictionary.Entry, 200)
+	for i := range entries {
+		entries[i] = dictionary.Entry{Word: randomString(rng, 2+rng.Intn(19)), Policy: dictionary.Policy{Match: dictionary.PolicyExact}}
+	}
+	line := randomString(rng, 2000)
+
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{ExactEngine: string(exactEngine)}}
+	matcher := NewMatcherFromEntries(entries, cfg, 100)
+	defer matcher.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = matcher.MatchLine(context.Background(), 1, line)
+	}
+}
+
+// utility to benchmark FindMatches with the given mode and engine over a random line of the given length.
+func benchmarkFindMatches(b *testing.B, mode Mode, engine Engine, lineLength int) {
+	rng := rand.New(rand.NewSource(1))
+	dict := make([]string, 100)
+	for i := range dict {
+		dict[i] = randomString(rng, 2+rng.Intn(19))
+	}
+	line := randomString(rng, lineLength)
+
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(mode), Engine: string(engine), MaxSpan: 40}}
+	matcher := NewMatcher(dict, cfg, 100)
+	defer matcher.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = matcher.FindMatches(context.Background(), line)
+	}
+}
+
+func BenchmarkFindMatches_WorkerPool(b *testing.B) {
+	matcher, line := newChunkBenchmark()
+	defer matcher.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		_, _ = matcher.FindMatches(context.Background(), line)
+	}
+}
+
+// BenchmarkFindMatches_GoroutinePerChunk is the baseline for BenchmarkFindMatches_WorkerPool, spawning a goroutine per chunk and merging behind a single mutex.
+func BenchmarkFindMatches_GoroutinePerChunk(b *testing.B) {
+	matcher, line := newChunkBenchmark()
+	defer matcher.Close()
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hits := make(map[hit]struct{})
+		var mutex sync.Mutex
+		var wg sync.WaitGroup
+		for _, c := range splitGraphemes(utils.Graphemes(line), matcher.chunkSize, matcher.overlap) {
+			wg.Add(1)
+			go func(c []utils.Grapheme) {
+				defer wg.Done()
+				localHits := processChunk(context.Background(), c, line, matcher.trie, matcher.keyFunc, matcher.prefixFunc, matcher.maxCounts)
+				mutex.Lock()
+				defer mutex.Unlock()
+				for _, h := range localHits {
+					hits[h] = struct{}{}
+				}
+			}(c)
+		}
+		wg.Wait()
+	}
+}
+
+// utility to build a matcher with a small chunk size and a long line, so that a line is split into thousands of chunks.
+func newChunkBenchmark() (*Matcher, string) {
+	rng := rand.New(rand.NewSource(1))
+	dict := make([]string, 100)
+	for i := range dict {
+		dict[i] = randomString(rng, 2+rng.Intn(4))
+	}
+	return NewMatcher(dict, config.AppConfig{}, 4), randomString(rng, 20000)
+}
+
+// TestMatcher_NonLatinDictionaries checks that scrambled words are found in scripts whose characters take several bytes.
+func TestMatcher_NonLatinDictionaries(t *testing.T) {
+	tests := []struct {
+		name     string
+		dict     []string
+		input    string
+		expected []string
+	}{
+		{name: "greek", dict: []string{"λόγος", "άλφα"}, input: "ξξγλοόςξξ", expected: []string{"λόγος"}},
+		{name: "cyrillic", dict: []string{"слово", "мир"}, input: "аавослоирмб", expected: []string{"слово", "мир"}},
+		{name: "devanagari", dict: []string{"नमस्ते"}, input: "कस्तेनमक", expected: []string{"नमस्ते"}},
+		{name: "japanese", dict: []string{"ことば", "にほん"}, input: "あばとこんほにい", expected: []string{"ことば", "にほん"}},
+		{name: "emoji", dict: []string{"👍🏽🇳🇿❤️"}, input: "x🇳🇿❤️👍🏽x", expected: []string{"👍🏽🇳🇿❤️"}},
+	}
+
+	for _, tt := range tests {
+		for _, engine := range []Engine{EngineTrie, EngineWindow} {
+			cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Engine: string(engine)}}
+			matcher := NewMatcher(tt.dict, cfg, 3)
+			defer matcher.Close()
+
+			matches, err := matcher.MatchLine(context.Background(), 1, tt.input)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, UniqueWords(matches), "script=%s engine=%s", tt.name, engine)
+			for _, match := range matches {
+				assert.Equal(t, match.Text, tt.input[match.Offset:match.Offset+len(match.Text)], "script=%s engine=%s", tt.name, engine)
+			}
+		}
+	}
+}
+
+// TestMatcher_GraphemesStayTogether checks that combining accents and emoji modifiers are never separated from their base character.
+func TestMatcher_GraphemesStayTogether(t *testing.T) {
+	// "e" followed by a combining acute accent, which must not be scrambled on its own.
+	dict := []string{"cafe\u0301"}
+	matcher := NewMatcher(dict, config.AppConfig{}, 2)
+	defer matcher.Close()
+
+	matches, err := matcher.MatchLine(context.Background(), 1, "xe\u0301facx")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cafe\u0301"}, UniqueWords(matches))
+
+	matches, err = matcher.MatchLine(context.Background(), 1, "xef\u0301acx")
+	assert.NoError(t, err)
+	assert.Empty(t, matches, "An accent moved to another letter is a different character")
+}
+
+// TestMatcher_Normalization checks that composed and decomposed spellings only match each other once normalized.
+func TestMatcher_Normalization(t *testing.T) {
+	composed := "caf\u00e9"
+	decomposed := "cafe\u0301"
+
+	matcher := NewMatcher([]string{composed}, config.AppConfig{}, 10)
+	defer matcher.Close()
+	matches, err := matcher.MatchLine(context.Background(), 1, decomposed)
+	assert.NoError(t, err)
+	assert.Empty(t, matches, "Without normalization composed and decomposed text differ")
+
+	for _, form := range []string{"nfc", "nfd", "nfkc", "nfkd"} {
+		cfg := config.AppConfig{TextConfig: config.TextConfig{Normalization: form}}
+		matcher := NewMatcher([]string{composed}, cfg, 10)
+		defer matcher.Close()
+
+		matches, err := matcher.MatchLine(context.Background(), 1, "x"+decomposed+"x")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{composed}, UniqueWords(matches), "form=%s", form)
+		assert.True(t, matches[0].Exact, "form=%s", form)
+	}
+}
+
+// TestMatcher_OffsetsReferToInputLine checks that the text and offset of matches slice the input line as given, however it was normalized.
+func TestMatcher_OffsetsReferToInputLine(t *testing.T) {
+	input := "Le CAFE\u0301, la Straße; \ufb01x!"
+	cfg := config.AppConfig{
+		MatcherConfig: config.MatcherConfig{Mode: string(ModeExact)},
+		TextConfig:    config.TextConfig{Normalization: "nfkd", FoldAccents: true, FoldCase: true},
+	}
+	cfg.IgnorePunctuation = true
+
+	for _, engine := range []Engine{EngineTrie, EngineWindow, EngineAhoCorasick} {
+		cfg.Engine = string(engine)
+		matcher := NewMatcher([]string{"café", "strasse", "fix"}, cfg, 4)
+		defer matcher.Close()
+
+		matches, err := matcher.MatchLine(context.Background(), 1, input)
+		assert.NoError(t, err)
+		assert.Equal(t, []Match{
+			{Word: "café", Text: "CAFE\u0301", Offset: 3, Line: 1, Exact: true},
+			{Word: "strasse", Text: "Straße", Offset: 14, Line: 1, Exact: true},
+			{Word: "fix", Text: "\ufb01x", Offset: 23, Line: 1, Exact: true},
+		}, matches, "engine=%s", engine)
+		for _, match := range matches {
+			assert.Equal(t, match.Text, input[match.Offset:match.Offset+len(match.Text)], "engine=%s", engine)
+		}
+	}
+}
+
+// TestMatcher_FoldAccents checks that accents are ignored once folded, on both sides of the match.
+func TestMatcher_FoldAccents(t *testing.T) {
+	cfg := config.AppConfig{TextConfig: config.TextConfig{FoldAccents: true}}
+	matcher := NewMatcher([]string{"café", "naïve"}, cfg, 10)
+	defer matcher.Close()
+
+	matches, err := matcher.MatchLine(context.Background(), 1, "efacxevian")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"café", "naïve"}, UniqueWords(matches))
+	assert.Equal(t, 2, matcher.CountUniqueMatches(map[string]struct{}{"éfac": {}, "evïan": {}}))
+}
+
+// TestMatcher_MultiByteChunkBoundaries checks that chunking multi-byte text never changes the result of a whole-line scan.
+func TestMatcher_MultiByteChunkBoundaries(t *testing.T) {
+	rng := rand.New(rand.NewSource(7))
+	alphabet := []string{"é", "ж", "λ", "e\u0301", "👍🏽", "字"}
+	randomText := func(n int) string {
+		s := ""
+		for i := 0; i < n; i++ {
+			s += alphabet[rng.Intn(len(alphabet))]
+		}
+		return s
+	}
+
+	for iteration := 0; iteration < 100; iteration++ {
+		dict := make([]string, 1+rng.Intn(4))
+		for i := range dict {
+			dict[i] = randomText(2 + rng.Intn(4))
+		}
+		line := randomText(1 + rng.Intn(40))
+		chunkSize := 1 + rng.Intn(10)
+
+		matcher := NewMatcher(dict, config.AppConfig{}, chunkSize)
+		defer matcher.Close()
+		expected := make(map[string]struct{})
+		for _, h := range processChunk(context.Background(), utils.Graphemes(line), line, matcher.trie, matcher.keyFunc, matcher.prefixFunc, matcher.maxCounts) {
+			expected[h.text] = struct{}{}
+		}
+
+		matches, err := matcher.FindMatches(context.Background(), line)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, matches, "dict=%q line=%q chunkSize=%d", dict, line, chunkSize)
+	}
+}
+
+// TestMatcher_FoldCase checks that case is ignored once folded, on both sides of the match.
+func TestMatcher_FoldCase(t *testing.T) {
+	dict := []string{"Apple", "STRASSE"}
+
+	matcher := NewMatcher(dict, config.AppConfig{}, 10)
+	defer matcher.Close()
+	matches, err := matcher.MatchLine(context.Background(), 1, "pplea")
+	assert.NoError(t, err)
+	assert.Empty(t, matches, "Without case folding words are compared as they are")
+
+	for _, engine := range []Engine{EngineTrie, EngineWindow} {
+		cfg := config.AppConfig{
+			MatcherConfig: config.MatcherConfig{Engine: string(engine)},
+			TextConfig:    config.TextConfig{FoldCase: true},
+		}
+		matcher := NewMatcher(dict, cfg, 3)
+		defer matcher.Close()
+
+		matches, err := matcher.MatchLine(context.Background(), 1, "xPpLEa Straße")
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Apple", "STRASSE"}, UniqueWords(matches), "engine=%s", engine)
+		assert.Equal(t, 2, matcher.CountUniqueMatches(map[string]struct{}{"PPLEA": {}, "straße": {}}), "engine=%s", engine)
+	}
+}
+
+// TestMatcher_IgnorePunctuation checks that punctuation and whitespace inside the input are skipped when forming candidates.
+func TestMatcher_IgnorePunctuation(t *testing.T) {
+	dict := []string{"apple", "don't"}
+	input := "An a-p, p l e; and 'ton d"
+
+	matcher := NewMatcher(dict, config.AppConfig{}, 10)
+	defer matcher.Close()
+	matches, err := matcher.MatchLine(context.Background(), 1, input)
+	assert.NoError(t, err)
+	assert.Empty(t, matches, "Without ignoring punctuation candidates are contiguous substrings")
+
+	for _, engine := range []Engine{EngineTrie, EngineWindow} {
+		cfg := config.AppConfig{
+			InputConfig:   config.InputConfig{IgnorePunctuation: true},
+			MatcherConfig: config.MatcherConfig{Engine: string(engine)},
+		}
+		matcher := NewMatcher(dict, cfg, 3)
+		defer matcher.Close()
+
+		matches, err := matcher.MatchLine(context.Background(), 1, input)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"apple", "don't"}, UniqueWords(matches), "engine=%s", engine)
+		for _, match := range matches {
+			assert.Equal(t, match.Text, input[match.Offset:match.Offset+len(match.Text)], "engine=%s", engine)
+		}
+	}
+}
+
+// TestMatcher_DuplicateKeys checks that dictionary words sharing a key are reported, and all resolved from a single occurrence.
+func TestMatcher_DuplicateKeys(t *testing.T) {
+	dict := []string{"tab", "cat", "bat", "act", "dog"}
+	matcher := NewMatcher(dict, config.AppConfig{}, 10)
+	defer matcher.Close()
+
+	assert.Equal(t, map[string][]string{
+		"abt": {"tab", "bat"},
+		"act": {"cat", "act"},
+	}, matcher.DuplicateKeys())
+
+	matches, err := matcher.MatchLine(context.Background(), 1, "xbtax")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"tab", "bat"}, UniqueWords(matches))
+	assert.Equal(t, 2, matcher.CountUniqueMatches(map[string]struct{}{"bta": {}}))
+
+	unique := NewMatcher([]string{"tab", "dog"}, config.AppConfig{}, 10)
+	defer unique.Close()
+	assert.Empty(t, unique.DuplicateKeys())
+}
+
+// TestMatcher_AhoCorasickEqualsTrieEngine checks that the Aho–Corasick engine finds the same exact occurrences as the trie engine.
+func TestMatcher_AhoCorasickEqualsTrieEngine(t *testing.T) {
+	rng := rand.New(rand.NewSource(11))
+	exact := config.MatcherConfig{Mode: string(ModeExact)}
+
+	for iteration := 0; iteration < 200; iteration++ {
+		dict := make([]string, 1+rng.Intn(8))
+		for i := range dict {
+			dict[i] = randomString(rng, 1+rng.Intn(5))
+		}
+		line := randomString(rng, 1+rng.Intn(80))
+
+		trieCfg, ahoCorasickCfg := exact, exact
+		trieCfg.Engine = string(EngineTrie)
+		ahoCorasickCfg.Engine = string(EngineAhoCorasick)
+		trieMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: trieCfg}, 10)
+		defer trieMatcher.Close()
+		ahoCorasickMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: ahoCorasickCfg}, 10)
+		defer ahoCorasickMatcher.Close()
+
+		trieMatches, err := trieMatcher.MatchLine(context.Background(), 1, line)
+		assert.NoError(t, err)
+		ahoCorasickMatches, err := ahoCorasickMatcher.MatchLine(context.Background(), 1, line)
+		assert.NoError(t, err)
+
+		assert.Equal(t, trieMatches, ahoCorasickMatches, "dict=%v line=%q", dict, line)
+		for _, match := range ahoCorasickMatches {
+			assert.True(t, match.Exact, "dict=%v line=%q", dict, line)
+		}
+	}
+}
+
+// TestMatcher_AhoCorasickGraphemeBoundaries checks that exact occurrences never start or end inside a character.
+func TestMatcher_AhoCorasickGraphemeBoundaries(t *testing.T) {
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(ModeExact), Engine: string(EngineAhoCorasick)}}
+	matcher := NewMatcher([]string{"cafe", "e\u0301t", "\u0301t"}, cfg, 10)
+	defer matcher.Close()
+
+	matches, err := matcher.MatchLine(context.Background(), 1, "cafe\u0301te\u0301t")
+	assert.NoError(t, err)
+	assert.Equal(t, []Match{
+		{Word: "e\u0301t", Text: "e\u0301t", Offset: 3, Line: 1, Exact: true},
+		{Word: "e\u0301t", Text: "e\u0301t", Offset: 7, Line: 1, Exact: true},
+	}, matches)
+}
+
+// TestMatcher_AhoCorasickRequiresExactMode checks that the Aho–Corasick engine is rejected in scrambled modes.
+func TestMatcher_AhoCorasickRequiresExactMode(t *testing.T) {
+	assert.NoError(t, CheckEngine(EngineAhoCorasick, ModeExact))
+	assert.Error(t, CheckEngine(EngineAhoCorasick, ModeAnagram))
+	assert.NoError(t, CheckEngine(EngineWindow, ModeExact))
+
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(ModeAnagram), Engine: string(EngineAhoCorasick)}}
+	matcher := NewMatcher([]string{"axpaj"}, cfg, 10)
+	defer matcher.Close()
+	assert.Equal(t, EngineTrie, matcher.engine, "A scrambled mode falls back to the trie engine")
+}
+
+// TestMatcher_FuzzyEngine checks that the fuzzy engine finds near-miss occurrences along with their distance, once per location.
+func TestMatcher_FuzzyEngine(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		metric   Metric
+		dict     []string
+		input    string
+		expected []Match
+	}{
+		{
+			mode:  ModeExact,
+			dict:  []string{"apple"},
+			input: "an aple, a apple",
+			expected: []Match{
+				{Word: "apple", Text: "aple", Offset: 3, Line: 1, Distance: 1},
+				{Word: "apple", Text: "apple", Offset: 11, Line: 1, Exact: true},
+			},
+		},
+		{
+			mode:  ModeAnagram,
+			dict:  []string{"axpaj"},
+			input: "zzapxjqzz",
+			expected: []Match{
+				{Word: "axpaj", Text: "zapxj", Offset: 1, Line: 1, Distance: 1},
+			},
+		},
+		{
+			mode:  ModeFixedEnds,
+			dict:  []string{"abcd", "axpaj"},
+			input: "dcba apxaj axpzaj",
+			expected: []Match{
+				{Word: "axpaj", Text: "apxaj", Offset: 5, Line: 1},
+				{Word: "axpaj", Text: "axpzaj", Offset: 11, Line: 1, Distance: 1},
+			},
+		},
+		{
+			mode:   ModeExact,
+			metric: MetricMultiset,
+			dict:   []string{"axpaj", "dnrbt"},
+			input:  "aapxjdnrbt",
+			expected: []Match{
+				{Word: "axpaj", Text: "aapxj", Offset: 0, Line: 1},
+				{Word: "dnrbt", Text: "dnrbt", Offset: 5, Line: 1, Exact: true},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{
+			Mode:           string(tt.mode),
+			Engine:         string(EngineFuzzy),
+			MaxDistance:    1,
+			DistanceMetric: string(tt.metric),
+		}}
+		matcher := NewMatcher(tt.dict, cfg, 10)
+		defer matcher.Close()
+		matches, err := matcher.MatchLine(context.Background(), 1, tt.input)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, matches, "mode=%s input=%q", tt.mode, tt.input)
+	}
+}
+
+// TestMatcher_FuzzyEngineWithoutDistanceEqualsTrieEngine checks that a maximum distance of 0 finds the same words as the trie engine.
+func TestMatcher_FuzzyEngineWithoutDistanceEqualsTrieEngine(t *testing.T) {
+	rng := rand.New(rand.NewSource(13))
+
+	for _, mode := range []Mode{ModeAnagram, ModeFixedEnds, ModeExact} {
+		for iteration := 0; iteration < 100; iteration++ {
+			dict := make([]string, 1+rng.Intn(8))
+			for i := range dict {
+				dict[i] = randomString(rng, 2+rng.Intn(4))
+			}
+			line := randomString(rng, 1+rng.Intn(60))
+
+			trieMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(mode)}}, 10)
+			defer trieMatcher.Close()
+			fuzzyMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(mode), Engine: string(EngineFuzzy)}}, 10)
+			defer fuzzyMatcher.Close()
+
+			trieMatches, err := trieMatcher.MatchLine(context.Background(), 1, line)
+			assert.NoError(t, err)
+			fuzzyMatches, err := fuzzyMatcher.MatchLine(context.Background(), 1, line)
+			assert.NoError(t, err)
+
+			assert.ElementsMatch(t, UniqueWords(trieMatches), UniqueWords(fuzzyMatches), "mode=%s dict=%v line=%q", mode, dict, line)
+			for _, match := range fuzzyMatches {
+				assert.Zero(t, match.Distance, "mode=%s dict=%v line=%q", mode, dict, line)
+			}
+		}
+	}
+}
+
+// TestParseMetric checks metric names, the default metric of every mode and the modes every metric supports.
+func TestParseMetric(t *testing.T) {
+	metric, err := ParseMetric("", ModeExact)
+	assert.NoError(t, err)
+	assert.Equal(t, MetricLevenshtein, metric)
+	metric, err = ParseMetric("", ModeFixedEnds)
+	assert.NoError(t, err)
+	assert.Equal(t, MetricMultiset, metric)
+	metric, err = ParseMetric("multiset", ModeExact)
+	assert.NoError(t, err)
+	assert.Equal(t, MetricMultiset, metric)
+	_, err = ParseMetric("hamming", ModeExact)
+	assert.Error(t, err)
+
+	assert.NoError(t, CheckMetric(MetricLevenshtein, ModeExact))
+	assert.Error(t, CheckMetric(MetricLevenshtein, ModeAnagram))
+	assert.NoError(t, CheckMetric(MetricMultiset, ModeAnagram))
+
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Engine: string(EngineFuzzy), DistanceMetric: string(MetricLevenshtein)}}
+	matcher := NewMatcher([]string{"axpaj"}, cfg, 10)
+	defer matcher.Close()
+	assert.Equal(t, MetricMultiset, matcher.fuzzy.metric, "A scrambled mode falls back to the multiset metric")
+}
+
+// TestMatcher_GappedEngine checks that the gapped engine finds words spread across filler characters, reporting the window they span.
+func TestMatcher_GappedEngine(t *testing.T) {
+	tests := []struct {
+		mode     Mode
+		maxSpan  int
+		input    string
+		expected []Match
+	}{
+		{
+			mode:    ModeAnagram,
+			maxSpan: 9,
+			input:   "see a-x-p-a-j and j..a..p..x..a",
+			expected: []Match{
+				{Word: "axpaj", Text: "a-x-p-a-j", Offset: 4, Line: 1},
+				{Word: "axpaj", Text: "x-p-a-j a", Offset: 6, Line: 1},
+			},
+		},
+		{
+			mode:    ModeAnagram,
+			maxSpan: 13,
+			input:   "j..a..p..x..a",
+			expected: []Match{
+				{Word: "axpaj", Text: "j..a..p..x..a", Offset: 0, Line: 1},
+			},
+		},
+		{
+			mode:    ModeExact,
+			maxSpan: 9,
+			input:   "a-x-p-a-j and j..a..p..x..a, axpaj",
+			expected: []Match{
+				{Word: "axpaj", Text: "a-x-p-a-j", Offset: 0, Line: 1},
+				{Word: "axpaj", Text: "axpaj", Offset: 29, Line: 1, Exact: true},
+			},
+		},
+		{
+			mode:    ModeFixedEnds,
+			maxSpan: 9,
+			input:   "a-p-x-a-j and x-a-p-a-j",
+			expected: []Match{
+				{Word: "axpaj", Text: "a-p-x-a-j", Offset: 0, Line: 1},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(tt.mode), Engine: string(EngineGapped), MaxSpan: tt.maxSpan}}
+		matcher := NewMatcher([]string{"axpaj"}, cfg, 10)
+		defer matcher.Close()
+		matches, err := matcher.MatchLine(context.Background(), 1, tt.input)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.expected, matches, "mode=%s input=%q", tt.mode, tt.input)
+	}
+}
+
+// TestMatcher_GappedEngineFindsContiguousMatches checks that every occurrence found by the trie engine is also found by the gapped engine.
+func TestMatcher_GappedEngineFindsContiguousMatches(t *testing.T) {
+	rng := rand.New(rand.NewSource(17))
+
+	for _, mode := range []Mode{ModeAnagram, ModeFixedEnds, ModeExact} {
+		for iteration := 0; iteration < 100; iteration++ {
+			dict := make([]string, 1+rng.Intn(8))
+			for i := range dict {
+				dict[i] = randomString(rng, 1+rng.Intn(5))
+			}
+			line := randomString(rng, 1+rng.Intn(60))
+
+			trieMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(mode)}}, 10)
+			defer trieMatcher.Close()
+			gappedMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(mode), Engine: string(EngineGapped), MaxSpan: 8}}, 10)
+			defer gappedMatcher.Close()
+
+			trieMatches, err := trieMatcher.MatchLine(context.Background(), 1, line)
+			assert.NoError(t, err)
+			gappedMatches, err := gappedMatcher.MatchLine(context.Background(), 1, line)
+			assert.NoError(t, err)
+
+			assert.Subset(t, gappedMatches, trieMatches, "mode=%s dict=%v line=%q", mode, dict, line)
+		}
+	}
+}
+
+// TestMatcher_CompactTrieEqualsMapTrie checks that the engines looking keys up find the same matches in either trie implementation,
+// and that the engines walking the nodes of the trie fall back to the map trie.
+func TestMatcher_CompactTrieEqualsMapTrie(t *testing.T) {
+	rng := rand.New(rand.NewSource(19))
+
+	for _, engine := range []Engine{EngineTrie, EngineWindow, EngineGapped} {
+		for iteration := 0; iteration < 50; iteration++ {
+			dict := make([]string, 1+rng.Intn(8))
+			for i := range dict {
+				dict[i] = randomString(rng, 1+rng.Intn(5))
+			}
+			line := randomString(rng, 1+rng.Intn(60))
+
+			cfg := config.MatcherConfig{Engine: string(engine), MaxSpan: 8}
+			mapMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: cfg}, 10)
+			defer mapMatcher.Close()
+			cfg.TrieImpl = string(utils.TrieCompact)
+			compactMatcher := NewMatcher(dict, config.AppConfig{MatcherConfig: cfg}, 10)
+			defer compactMatcher.Close()
+
+			mapMatches, err := mapMatcher.MatchLine(context.Background(), 1, line)
+			assert.NoError(t, err)
+			compactMatches, err := compactMatcher.MatchLine(context.Background(), 1, line)
+			assert.NoError(t, err)
+
+			assert.Equal(t, mapMatches, compactMatches, "engine=%s dict=%v line=%q", engine, dict, line)
+		}
+	}
+
+	assert.Error(t, CheckTrieImpl(utils.TrieCompact, EngineAhoCorasick))
+	assert.NoError(t, CheckTrieImpl(utils.TrieCompact, EngineWindow))
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(ModeExact), Engine: string(EngineAhoCorasick), TrieImpl: string(utils.TrieCompact)}}
+	matcher := NewMatcher([]string{"axpaj"}, cfg, 10)
+	defer matcher.Close()
+	assert.NotNil(t, matcher.automaton, "The Aho–Corasick engine falls back to the map trie")
+}
+
+// TestCombinedMatcher_MergesMatches checks that an exact and a scrambled matcher combine into a single ordered list of matches.
+func TestCombinedMatcher_MergesMatches(t *testing.T) {
+	dict := []string{"axpaj", "dnrbt"}
+	input := "aapxjdnrbtaxpaj"
+	exact := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(ModeExact), Engine: string(EngineAhoCorasick)}}
+
+	combined := NewCombinedMatcher(NewMatcher(dict, exact, 10), NewMatcher(dict, config.AppConfig{}, 10))
+	defer combined.Close()
+	scrambled := NewMatcher(dict, config.AppConfig{}, 10)
+	defer scrambled.Close()
+
+	expected, err := scrambled.MatchLine(context.Background(), 1, input)
+	assert.NoError(t, err)
+	matches, err := combined.MatchLine(context.Background(), 1, input)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, matches)
+	assert.Equal(t, []bool{false, true, true}, []bool{matches[0].Exact, matches[1].Exact, matches[2].Exact})
+}
+
+// TestNewMatcherFromEntries_Policies checks that every word is matched according to its own policy.
+func TestNewMatcherFromEntries_Policies(t *testing.T) {
+	entries := []dictionary.Entry{
+		{Word: "apple", Policy: dictionary.Policy{Match: dictionary.PolicyExact}},
+		{Word: "Berry", Policy: dictionary.Policy{Match: dictionary.PolicyScrambled, IgnoreCase: true}},
+		{Word: "cherry"},
+	}
+
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{input: "pplea yrrehc", expected: []string{"cherry"}},
+		{input: "apple", expected: []string{"apple"}},
+		{input: "RYBER", expected: []string{"Berry"}},
+		{input: "yrreb CHERRY", expected: []string{"Berry"}},
+	}
+
+	for _, engine := range []Engine{EngineTrie, EngineWindow} {
+		cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Engine: string(engine), ExactEngine: string(EngineAhoCorasick)}}
+		matcher := NewMatcherFromEntries(entries, cfg, 10)
+		defer matcher.Close()
+		for _, tt := range tests {
+			matches, err := matcher.MatchLine(context.Background(), 1, tt.input)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, UniqueWords(matches), "engine=%s input=%q", engine, tt.input)
+		}
+	}
+}
+
+// TestNewMatcherFromEntries_ScrambledInExactMode checks that scrambled words are still scrambled when the configured mode is exact.
+func TestNewMatcherFromEntries_ScrambledInExactMode(t *testing.T) {
+	entries := []dictionary.Entry{
+		{Word: "apple"},
+		{Word: "berry", Policy: dictionary.Policy{Match: dictionary.PolicyScrambled}},
+	}
+	cfg := config.AppConfig{MatcherConfig: config.MatcherConfig{Mode: string(ModeExact), Engine: string(EngineAhoCorasick)}}
+	matcher := NewMatcherFromEntries(entries, cfg, 10)
+	defer matcher.Close()
+
+	matches, err := matcher.MatchLine(context.Background(), 1, "pplea yrreb apple")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"berry", "apple"}, UniqueWords(matches))
+}
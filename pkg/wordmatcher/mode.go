@@ -0,0 +1,124 @@
+package wordmatcher
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// Mode selects how dictionary words and input substrings are reduced to comparable keys.
+type Mode string
+
+const (
+	// ModeAnagram matches any permutation of a dictionary word.
+	ModeAnagram Mode = "anagram"
+	// ModeFixedEnds matches permutations that keep the first and last letters in place, as in the Code Jam scrambled words problem.
+	ModeFixedEnds Mode = "fixed-ends"
+	// ModeExact only matches dictionary words as they are, unscrambled.
+	ModeExact Mode = "exact"
+)
+
+// keyFunc reduces a word, split into user-perceived characters, to the key under which it is stored in, and looked up from, the trie.
+type keyFunc func(graphemes []utils.Grapheme) string
+
+// prefixFunc reduces a substring, split into user-perceived characters, to a prefix of the keys of the substring and of all its extensions to the right.
+// once no key in the trie has the prefix, the scan of the substring's extensions stops.
+type prefixFunc func(graphemes []utils.Grapheme) string
+
+// graphemeCounts holds the largest number of times every character occurs in any dictionary word, characters missing from every word occur 0 times.
+// the key of an anagram holds every character of the substring, so once a substring has a character more often than any word, none of its extensions to the right is a hit either.
+type graphemeCounts map[string]int
+
+// ParseMode converts the given name into a Mode, an empty name selects ModeAnagram.
+func ParseMode(name string) (Mode, error) {
+	switch Mode(name) {
+	case "", ModeAnagram:
+		return ModeAnagram, nil
+	case ModeFixedEnds:
+		return ModeFixedEnds, nil
+	case ModeExact:
+		return ModeExact, nil
+	default:
+		return "", fmt.Errorf("unknown match mode %q, expected one of: %s, %s, %s", name, ModeAnagram, ModeFixedEnds, ModeExact)
+	}
+}
+
+// returns the key function implementing the given mode.
+func (mode Mode) keyFunc() keyFunc {
+	switch mode {
+	case ModeFixedEnds:
+		return generateFixedEndsKey
+	case ModeExact:
+		return joinGraphemes
+	default:
+		return generateKey
+	}
+}
+
+// returns the prefix function of the given mode, or nil if its keys share no prefix with those of their extensions.
+// in exact mode a key is the substring itself, in fixed-ends mode every key starts with the first character, and anagram keys start with the smallest character, which changes as the substring grows.
+// anagram substrings are pruned by their character counts instead, see graphemeCounts.
+func (mode Mode) prefixFunc() prefixFunc {
+	switch mode {
+	case ModeFixedEnds:
+		return firstGrapheme
+	case ModeExact:
+		return joinGraphemes
+	default:
+		return nil
+	}
+}
+
+// utility to generate a key for a given word, by sorting its characters to account for anagrams.
+// characters are whole graphemes, so a letter and its combining accents always move together.
+func generateKey(graphemes []utils.Grapheme) string {
+	chars := make([]string, len(graphemes))
+	for i, g := range graphemes {
+		chars[i] = g.Text
+	}
+	sort.Strings(chars)
+	return strings.Join(chars, "")
+}
+
+// utility to generate a key for a given word, by sorting only the characters between the first and the last one.
+func generateFixedEndsKey(graphemes []utils.Grapheme) string {
+	if len(graphemes) <= 3 {
+		return joinGraphemes(graphemes)
+	}
+	last := len(graphemes) - 1
+	return graphemes[0].Text + generateKey(graphemes[1:last]) + graphemes[last].Text
+}
+
+// utility to join graphemes back into a string, this is also the key of a word in exact mode.
+func joinGraphemes(graphemes []utils.Grapheme) string {
+	var b strings.Builder
+	for _, g := range graphemes {
+		b.WriteString(g.Text)
+	}
+	return b.String()
+}
+
+// utility to return the first of the given graphemes, this is the prefix of every key in fixed-ends mode.
+func firstGrapheme(graphemes []utils.Grapheme) string {
+	return graphemes[0].Text
+}
+
+// utility to count the largest number of times every character occurs in any of the given words, each split into graphemes.
+func maxGraphemeCounts(words [][]utils.Grapheme) graphemeCounts {
+	maxCounts := make(graphemeCounts)
+	counts := make(map[string]int)
+	for _, graphemes := range words {
+		for _, g := range graphemes {
+			counts[g.Text]++
+		}
+		for _, g := range graphemes {
+			if counts[g.Text] > maxCounts[g.Text] {
+				maxCounts[g.Text] = counts[g.Text]
+			}
+			delete(counts, g.Text)
+		}
+	}
+	return maxCounts
+}
@@ -0,0 +1,141 @@
+package wordmatcher
+
+import (
+	"github.com/1x-eng/cipherlex/pkg/config"
+	"github.com/1x-eng/cipherlex/pkg/dictionary"
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// policyGroup holds the words of a dictionary sharing the same effective mode and case folding, along with the configuration matching them.
+type policyGroup struct {
+	cfg        config.AppConfig
+	dictionary string
+	words      []CompiledWord
+}
+
+// NewMatcherFromEntries creates a LineMatcher for the given dictionary entries, honouring the match policy of every entry.
+// entries are grouped by their dictionary, effective mode and case folding, every group is matched by its own Matcher and their matches are combined.
+// matches are tagged with the dictionary of their word, so a word present in several dictionaries yields a match per dictionary.
+// with an exact engine configured, groups matched in exact mode, such as the words of an exact policy, use it instead of the configured engine,
+// so they are left out of the substring scan of the scrambled groups. exact occurrences of scrambled words are scrambles too, and are found by that scan.
+func NewMatcherFromEntries(entries []dictionary.Entry, cfg config.AppConfig, chunkSize int) LineMatcher {
+	return NewMatcherFromCompiled(CompileEntries(entries, cfg), cfg, chunkSize)
+}
+
+// NewMatcherFromCompiled creates a LineMatcher for the given compiled words like NewMatcherFromEntries, without normalizing them again.
+// the words must have been compiled with the same mode and text settings as the given configuration.
+func NewMatcherFromCompiled(words []CompiledWord, cfg config.AppConfig, chunkSize int) LineMatcher {
+	groups := groupByPolicy(words, cfg)
+	if len(groups) == 0 {
+		return NewMatcher(nil, cfg, chunkSize)
+	}
+
+	var matchers []LineMatcher
+	for _, group := range groups {
+		matchers = append(matchers, newDictionaryMatcher(group.dictionary, group.words, group.cfg, chunkSize))
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"groupCount":   len(groups),
+		"matcherCount": len(matchers),
+	}).Debug("Created matchers for dictionary policies")
+
+	if len(matchers) == 1 {
+		return matchers[0]
+	}
+	return NewCombinedMatcher(matchers...)
+}
+
+// creates a new Matcher for the compiled words of the dictionary with the given name.
+func newDictionaryMatcher(dictionary string, words []CompiledWord, cfg config.AppConfig, chunkSize int) *Matcher {
+	m := newMatcher(cfg, chunkSize)
+	m.dictionary = dictionary
+	m.insert(words, cfg)
+	return m
+}
+
+// utility to group the given words by their dictionary, effective mode and case folding, in order of first occurrence.
+// groups matched in exact mode use the exact engine, if one is configured.
+func groupByPolicy(words []CompiledWord, cfg config.AppConfig) []*policyGroup {
+	mode, err := ParseMode(cfg.Mode)
+	if err != nil {
+		mode = ModeAnagram
+	}
+	engine, err := ParseEngine(cfg.Engine)
+	if err != nil {
+		engine = EngineTrie
+	}
+
+	type groupKey struct {
+		dictionary string
+		mode       Mode
+		foldCase   bool
+	}
+	groups := make(map[groupKey]*policyGroup)
+	var ordered []*policyGroup
+	for _, word := range words {
+		key := groupKey{
+			dictionary: word.Entry.Dictionary,
+			mode:       policyMode(word.Entry.Policy, mode),
+			foldCase:   cfg.FoldCase || word.Entry.Policy.IgnoreCase,
+		}
+		group, exists := groups[key]
+		if !exists {
+			group = &policyGroup{cfg: policyConfig(cfg, key.mode, key.foldCase), dictionary: key.dictionary}
+			if CheckEngine(engine, key.mode) != nil {
+				group.cfg.Engine = string(EngineTrie)
+			}
+			if key.mode == ModeExact && cfg.ExactEngine != "" {
+				group.cfg = exactEngineConfig(group.cfg, cfg.ExactEngine)
+			}
+			if metric, err := ParseMetric(cfg.DistanceMetric, key.mode); err != nil || CheckMetric(metric, key.mode) != nil {
+				group.cfg.DistanceMetric = ""
+			}
+			groups[key] = group
+			ordered = append(ordered, group)
+		}
+		group.words = append(group.words, word)
+	}
+	return ordered
+}
+
+// utility to switch the given configuration of an exact mode group to the given exact engine, unless it cannot match exact occurrences.
+// the trie implementation falls back to the map trie when the exact engine walks its nodes.
+func exactEngineConfig(cfg config.AppConfig, exactEngine string) config.AppConfig {
+	engine, err := ParseEngine(exactEngine)
+	if err == nil {
+		err = CheckExactEngine(engine)
+	}
+	if err != nil {
+		utils.Log.WithError(err).Warn("Ignoring exact engine")
+		return cfg
+	}
+	cfg.Engine = string(engine)
+	if impl, err := utils.ParseTrieImpl(cfg.TrieImpl); err == nil && CheckTrieImpl(impl, engine) != nil {
+		cfg.TrieImpl = string(utils.TrieMap)
+	}
+	return cfg
+}
+
+// utility to derive the configuration matching words with the given effective mode and case folding.
+func policyConfig(cfg config.AppConfig, mode Mode, foldCase bool) config.AppConfig {
+	cfg.Mode = string(mode)
+	cfg.FoldCase = foldCase
+	return cfg
+}
+
+// utility to determine the mode a word with the given policy is matched in, given the configured mode.
+// scrambled words keep a scrambled configured mode, and fall back to anagram mode when the configured mode is exact.
+func policyMode(policy dictionary.Policy, mode Mode) Mode {
+	switch policy.Match {
+	case dictionary.PolicyExact:
+		return ModeExact
+	case dictionary.PolicyScrambled:
+		if mode == ModeExact {
+			return ModeAnagram
+		}
+		return mode
+	default:
+		return mode
+	}
+}
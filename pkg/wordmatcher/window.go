@@ -0,0 +1,118 @@
+package wordmatcher
+
+import (
+	"context"
+	"sort"
+
+	"github.com/1x-eng/cipherlex/pkg/utils"
+)
+
+// contextCheckInterval is how many window positions are processed between checks of the context.
+const contextCheckInterval = 1024
+
+// letterSignatures assigns a pseudo random value to every single byte character, the signature of a window is the sum of the values of its characters.
+// sums are order independent, so all permutations of a word share its signature and the window can be rolled in O(1).
+var letterSignatures = newLetterSignatures()
+
+// utility to fill the signature table deterministically.
+func newLetterSignatures() [256]uint64 {
+	var table [256]uint64
+	for i := range table {
+		table[i] = mix64(uint64(i) + 1)
+	}
+	return table
+}
+
+// utility to scramble a 64 bit value, using the splitmix64 finalizer.
+func mix64(z uint64) uint64 {
+	z += 0x9e3779b97f4a7c15
+	z = (z ^ (z >> 30)) * 0xbf58476d1ce4e5b9
+	z = (z ^ (z >> 27)) * 0x94d049bb133111eb
+	return z ^ (z >> 31)
+}
+
+// utility to compute the signature of a single character, multi-byte characters are hashed with FNV-1a.
+func graphemeSignature(grapheme string) uint64 {
+	if len(grapheme) == 1 {
+		return letterSignatures[grapheme[0]]
+	}
+	hash := uint64(14695981039346656037)
+	for i := 0; i < len(grapheme); i++ {
+		hash ^= uint64(grapheme[i])
+		hash *= 1099511628211
+	}
+	return mix64(hash)
+}
+
+// windowIndex groups the signatures of dictionary words by word length in characters, for the sliding window engine.
+type windowIndex struct {
+	lengths    []int
+	signatures map[int]map[uint64]struct{}
+}
+
+// creates a new windowIndex for the given dictionary words, each split into graphemes.
+func newWindowIndex(words [][]utils.Grapheme) *windowIndex {
+	w := &windowIndex{signatures: make(map[int]map[uint64]struct{})}
+	for _, graphemes := range words {
+		if len(graphemes) == 0 {
+			continue
+		}
+		sigs, exists := w.signatures[len(graphemes)]
+		if !exists {
+			sigs = make(map[uint64]struct{})
+			w.signatures[len(graphemes)] = sigs
+			w.lengths = append(w.lengths, len(graphemes))
+		}
+		sigs[signature(graphemes)] = struct{}{}
+	}
+	sort.Ints(w.lengths)
+	return w
+}
+
+// utility to compute the order independent signature of a word.
+func signature(graphemes []utils.Grapheme) uint64 {
+	var sig uint64
+	for _, g := range graphemes {
+		sig += graphemeSignature(g.Text)
+	}
+	return sig
+}
+
+// finds all hits in the given input string, split into the given graphemes, rolling one window per dictionary word length across it.
+// windows whose signature matches a dictionary word are confirmed against the trie, so signature collisions never produce false matches.
+// the context is checked every contextCheckInterval window positions, returning the hits found so far once it is done.
+func (w *windowIndex) findHits(ctx context.Context, input string, graphemes []utils.Grapheme, t utils.WordTrie, keyFunc keyFunc) ([]hit, error) {
+	hits := make(map[hit]struct{})
+	for _, length := range w.lengths {
+		if length > len(graphemes) {
+			break
+		}
+		sigs := w.signatures[length]
+		sig := signature(graphemes[:length])
+		for start := 0; ; start++ {
+			if start%contextCheckInterval == 0 && ctx.Err() != nil {
+				return sortedHits(hits), ctx.Err()
+			}
+			end := start + length
+			if _, ok := sigs[sig]; ok {
+				window := graphemes[start:end]
+				if key := keyFunc(window); t.Find(key) {
+					h := hit{offset: window[0].Offset, text: input[window[0].Offset:window[length-1].End()], key: key}
+					hits[h] = struct{}{}
+				}
+			}
+			if end >= len(graphemes) {
+				break
+			}
+			sig += graphemeSignature(graphemes[end].Text) - graphemeSignature(graphemes[start].Text)
+		}
+	}
+
+	utils.Log.WithFields(map[string]interface{}{
+		"inputLength": len(graphemes),
+		"lengths":     w.lengths,
+		"hitCount":    len(hits),
+	}).Debug("Slid signature windows across input")
+
+	return sortedHits(hits), nil
+}